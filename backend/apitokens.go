@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// apiTokenPrefix marks our tokens, so leaked ones are easy to scan for
+const apiTokenPrefix = "pfe_"
+
+var (
+	apiTokenDefaultDays = parseInt(getEnv("API_TOKEN_DEFAULT_DAYS", "90"))
+	apiTokenMaxDays     = parseInt(getEnv("API_TOKEN_MAX_DAYS", "365"))
+)
+
+// APIToken is a personal API token as listed to its owner. The secret itself
+// is only returned once, when the token is created.
+type APIToken struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Username   string   `json:"username,omitempty"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at,omitempty"`
+	LastUsedIP string   `json:"last_used_ip,omitempty"`
+	RevokedAt  *string  `json:"revoked_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// apiTokenAuth is the identity of a request authenticated with a token
+type apiTokenAuth struct {
+	id       int
+	name     string
+	userID   int
+	username string
+	scopes   []string
+}
+
+type apiTokenKey struct{}
+
+// apiTokenOf returns the token r was authenticated with, if any
+func apiTokenOf(r *http.Request) *apiTokenAuth {
+	t, _ := r.Context().Value(apiTokenKey{}).(*apiTokenAuth)
+	return t
+}
+
+// allows reports whether the token was granted perm. Requests without a token
+// are only limited by their role.
+func (t *apiTokenAuth) allows(perm string) bool {
+	if t == nil {
+		return true
+	}
+	for _, s := range t.scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// withAPIToken authenticates requests carrying "Authorization: Bearer". The
+// token then stands in for the session; an unknown, expired or revoked token
+// is rejected rather than falling back to the cookie.
+func withAPIToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next(w, r)
+			return
+		}
+		raw, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			respondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		t := &apiTokenAuth{}
+		var scopes pq.StringArray
+		err := db.QueryRow(`
+			UPDATE api_tokens t SET last_used_at = NOW(), last_used_ip = $2
+			FROM users u
+			WHERE u.id = t.user_id AND t.token_hash=$1
+				AND t.revoked_at IS NULL AND t.expires_at > NOW()
+			RETURNING t.id, t.name, t.user_id, u.username, t.scopes
+		`, hashToken(strings.TrimSpace(raw)), clientIP(r)).Scan(&t.id, &t.name, &t.userID, &t.username, &scopes)
+		if err == sql.ErrNoRows {
+			respondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			log.Printf("Error checking API token: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		t.scopes = scopes
+		next(w, r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, t)))
+	}
+}
+
+func formatOptionalTime(t sql.NullTime) *string {
+	if !t.Valid {
+		return nil
+	}
+	s := t.Time.Format(time.RFC3339)
+	return &s
+}
+
+// apiTokensHandler godoc
+// @Summary Manage personal API tokens
+// @Description Users with tokens:create: list their tokens (GET; ?all=true lists everyone's for users:manage), create one (POST), or revoke one (DELETE ?id=). A token acts as its owner, limited to its scopes, through "Authorization: Bearer". The secret is only returned on creation. Tokens cannot manage tokens.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param all query bool false "Every user's tokens (GET, users:manage)"
+// @Param id query int false "Token ID (DELETE)"
+// @Param body body object{name=string,scopes=[]string,expires_in_days=int} false "Token payload (POST)"
+// @Success 200 {array} APIToken
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /tokens [get]
+// @Router /tokens [post]
+// @Router /tokens [delete]
+func apiTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if apiTokenOf(r) != nil {
+		respondError(w, "API tokens cannot manage API tokens", http.StatusForbidden)
+		return
+	}
+	userID, role, _ := currentRole(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		where, args := `WHERE t.user_id=$1`, []interface{}{userID}
+		if r.URL.Query().Get("all") == "true" {
+			if !roleHas(role, PermUsersManage) {
+				respondError(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			where, args = ``, nil
+		}
+
+		rows, err := db.Query(`
+			SELECT t.id, t.name, u.username, t.scopes, t.expires_at, t.last_used_at,
+				COALESCE(t.last_used_ip, ''), t.revoked_at, t.created_at
+			FROM api_tokens t JOIN users u ON u.id = t.user_id
+			`+where+`
+			ORDER BY t.created_at DESC`, args...)
+		if err != nil {
+			log.Printf("Error fetching API tokens: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		tokens := []APIToken{}
+		for rows.Next() {
+			var t APIToken
+			var scopes pq.StringArray
+			var expires, created time.Time
+			var lastUsed, revoked sql.NullTime
+			if err := rows.Scan(&t.ID, &t.Name, &t.Username, &scopes, &expires, &lastUsed,
+				&t.LastUsedIP, &revoked, &created); err != nil {
+				log.Printf("Error scanning API token: %v", err)
+				continue
+			}
+			t.Scopes = scopes
+			t.ExpiresAt = expires.Format(time.RFC3339)
+			t.LastUsedAt = formatOptionalTime(lastUsed)
+			t.RevokedAt = formatOptionalTime(revoked)
+			t.CreatedAt = created.Format(time.RFC3339)
+			tokens = append(tokens, t)
+		}
+		respondJSON(w, tokens, http.StatusOK)
+
+	case http.MethodPost:
+		var body struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		body.Name = strings.TrimSpace(body.Name)
+		if body.Name == "" || len(body.Scopes) == 0 {
+			respondError(w, "Name and scopes are required", http.StatusBadRequest)
+			return
+		}
+		// A token can only be granted what its owner may do today
+		for _, s := range body.Scopes {
+			if !roleHas(role, s) {
+				respondError(w, "Scope not held by your role: "+s, http.StatusBadRequest)
+				return
+			}
+		}
+		if body.ExpiresInDays == 0 {
+			body.ExpiresInDays = apiTokenDefaultDays
+		}
+		if body.ExpiresInDays < 1 || body.ExpiresInDays > apiTokenMaxDays {
+			respondError(w, "Tokens expire after 1 to "+strconv.Itoa(apiTokenMaxDays)+" days", http.StatusBadRequest)
+			return
+		}
+
+		secret, err := randomString()
+		if err != nil {
+			log.Printf("Error generating API token: %v", err)
+			respondError(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		token := apiTokenPrefix + secret
+		expires := time.Now().AddDate(0, 0, body.ExpiresInDays)
+
+		var id int
+		err = db.QueryRow(`
+			INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, userID, body.Name, hashToken(token), pq.Array(body.Scopes), expires).Scan(&id)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			respondError(w, "You already have an active token with this name", http.StatusConflict)
+			return
+		} else if err != nil {
+			log.Printf("Error creating API token: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "api_tokens", strconv.Itoa(id), nil, map[string]interface{}{
+			"name": body.Name, "scopes": body.Scopes, "expires_at": expires.Format(time.RFC3339),
+		})
+		respondJSON(w, map[string]interface{}{
+			"id":         id,
+			"token":      token,
+			"expires_at": expires.Format(time.RFC3339),
+		}, http.StatusCreated)
+
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			respondError(w, "Invalid token ID", http.StatusBadRequest)
+			return
+		}
+
+		// Users revoke their own tokens; user managers anyone's
+		var name string
+		err = db.QueryRow(`
+			UPDATE api_tokens SET revoked_at = NOW()
+			WHERE id=$1 AND revoked_at IS NULL AND (user_id=$2 OR $3)
+			RETURNING name
+		`, id, userID, roleHas(role, PermUsersManage)).Scan(&name)
+		if err == sql.ErrNoRows {
+			respondError(w, "Token not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error revoking API token: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "api_tokens", strconv.Itoa(id), map[string]string{"name": name}, nil)
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
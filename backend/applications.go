@@ -0,0 +1,329 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Application statuses, in funnel order
+const (
+	StatusSubmitted   = "submitted"
+	StatusUnderReview = "under_review"
+	StatusShortlisted = "shortlisted"
+	StatusInterview   = "interview"
+	StatusAccepted    = "accepted"
+	StatusRejected    = "rejected"
+	StatusWithdrawn   = "withdrawn"
+)
+
+var applicationStatuses = []string{
+	StatusSubmitted, StatusUnderReview, StatusShortlisted,
+	StatusInterview, StatusAccepted, StatusRejected, StatusWithdrawn,
+}
+
+// closedStatuses no longer count as an open application, so the candidate
+// may apply again to the same campaign.
+var closedStatuses = []string{StatusRejected, StatusWithdrawn}
+
+func isClosedStatus(status string) bool {
+	for _, s := range closedStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func validStatus(status string) bool {
+	for _, s := range applicationStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// applicationColumns matches the scan order of scanApplication
+const applicationColumns = `
+	a.id, a.full_name, a.email, a.gender, a.phone, a.university,
+	a.field_of_study, a.degree_level, a.application_type,
+	a.internship_duration, a.preferred_working_method,
+	a.start_date, a.created_at, a.cv_file_path, a.motivation_file_path,
+	a.status, a.candidate_id, a.campaign_id,
+	a.consent_version, a.consented_at, a.keep_profile,
+	ARRAY(
+		SELECT s.name FROM subjects s
+		JOIN application_subjects aps ON aps.subject_id = s.id
+		WHERE aps.application_id = a.id ORDER BY s.name
+	)`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanApplication(row rowScanner) (ApplicationResponse, error) {
+	var a ApplicationResponse
+	var start sql.NullTime
+	var created time.Time
+	var campaignID, consentVersion sql.NullInt64
+	var consented sql.NullTime
+
+	if err := row.Scan(
+		&a.ID, &a.FullName, &a.Email, &a.Gender, &a.Phone,
+		&a.University, &a.FieldOfStudy, &a.DegreeLevel,
+		&a.ApplicationType, &a.InternshipDuration,
+		&a.PreferredWorkingMethod, &start,
+		&created, &a.CVFilePath, &a.MotivationFilePath,
+		&a.Status, &a.CandidateID, &campaignID,
+		&consentVersion, &consented, &a.KeepProfile,
+		pq.Array(&a.Subjects),
+	); err != nil {
+		return a, err
+	}
+
+	a.CreatedAt = created.Format("2006-01-02")
+	if start.Valid {
+		s := start.Time.Format("2006-01-02")
+		a.StartDate = &s
+	}
+	if campaignID.Valid {
+		id := int(campaignID.Int64)
+		a.CampaignID = &id
+	}
+	if consentVersion.Valid {
+		v := int(consentVersion.Int64)
+		a.ConsentVersion = &v
+	}
+	if consented.Valid {
+		s := consented.Time.Format(time.RFC3339)
+		a.ConsentedAt = &s
+	}
+	return a, nil
+}
+
+// queryApplications runs a SELECT of applicationColumns over applications
+// aliased as "a".
+func queryApplications(query string, args ...interface{}) ([]ApplicationResponse, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ApplicationResponse
+	for rows.Next() {
+		a, err := scanApplication(rows)
+		if err != nil {
+			log.Printf("Error scanning application: %v", err)
+			continue
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// applicationFilters turns the list query parameters into a WHERE clause
+// over applications aliased as "a". Placeholders are numbered from 1.
+func applicationFilters(q url.Values) (string, []interface{}, error) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	for _, f := range []struct{ param, column string }{
+		{"status", "a.status"},
+		{"gender", "a.gender"},
+		{"university", "a.university"},
+		{"field_of_study", "a.field_of_study"},
+		{"degree_level", "a.degree_level"},
+		{"application_type", "a.application_type"},
+		{"internship_duration", "a.internship_duration"},
+		{"preferred_working_method", "a.preferred_working_method"},
+	} {
+		if v := q.Get(f.param); v != "" {
+			add(f.column+" = ?", v)
+		}
+	}
+
+	for _, f := range []struct{ param, column string }{
+		{"campaign_id", "a.campaign_id"},
+		{"candidate_id", "a.candidate_id"},
+	} {
+		if v := q.Get(f.param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid %s", f.param)
+			}
+			add(f.column+" = ?", id)
+		}
+	}
+
+	if v := q.Get("ids"); v != "" {
+		var ids []int
+		for _, part := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return "", nil, errors.New("invalid ids")
+			}
+			ids = append(ids, id)
+		}
+		add("a.id = ANY(?)", pq.Array(ids))
+	}
+	if v := q.Get("subject"); v != "" {
+		add(`EXISTS (
+			SELECT 1 FROM application_subjects aps
+			JOIN subjects s ON s.id = aps.subject_id
+			WHERE aps.application_id = a.id AND s.name = ?)`, v)
+	}
+	if v := q.Get("reviewer_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return "", nil, errors.New("invalid reviewer_id")
+		}
+		add(`EXISTS (
+			SELECT 1 FROM application_assignments aa
+			WHERE aa.application_id = a.id AND aa.reviewer_id = ?)`, id)
+	}
+	switch q.Get("assigned") {
+	case "true":
+		conds = append(conds, `EXISTS (SELECT 1 FROM application_assignments aa WHERE aa.application_id = a.id)`)
+	case "false":
+		conds = append(conds, `NOT EXISTS (SELECT 1 FROM application_assignments aa WHERE aa.application_id = a.id)`)
+	}
+	if v := strings.TrimSpace(q.Get("q")); v != "" {
+		add("(a.full_name ILIKE ? OR a.email ILIKE ?)", "%"+v+"%")
+	}
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return "", nil, errors.New("invalid from date")
+		}
+		add("a.created_at >= ?", t)
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return "", nil, errors.New("invalid to date")
+		}
+		add("a.created_at < ?", t.AddDate(0, 0, 1))
+	}
+
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+// changeStatus moves an application to status and queues the candidate
+// email in the same transaction. It returns the previous status, or
+// sql.ErrNoRows for an unknown application and errApplicationAnonymized for
+// an anonymized one.
+func changeStatus(appID int, status string) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var previous, email, fullName string
+	var anonymized bool
+	if err := tx.QueryRow(`
+		SELECT status, email, full_name, anonymized_at IS NOT NULL FROM applications WHERE id=$1 FOR UPDATE
+	`, appID).Scan(&previous, &email, &fullName, &anonymized); err != nil {
+		return "", err
+	}
+	if anonymized {
+		return "", errApplicationAnonymized
+	}
+	if previous == status {
+		return previous, nil
+	}
+
+	if _, err := tx.Exec(`UPDATE applications SET status=$1 WHERE id=$2`, status, appID); err != nil {
+		return "", err
+	}
+
+	templateName, data := statusEmail(appID, fullName, previous, status)
+	if err := enqueueEmail(tx, email, templateName, data); err != nil {
+		return "", err
+	}
+
+	summary := fmt.Sprintf("Application #%d (%s) moved from %s to %s", appID, fullName, statusLabel(previous), statusLabel(status))
+	if err := enqueueWebhook(tx, EventApplicationStatusChanged, summary, map[string]interface{}{
+		"id":              appID,
+		"full_name":       fullName,
+		"email":           email,
+		"previous_status": previous,
+		"status":          status,
+	}); err != nil {
+		return "", err
+	}
+	return previous, tx.Commit()
+}
+
+// updateApplicationStatus godoc
+// @Summary Change application status
+// @Description Admin: move an application through the review funnel
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int,status=string} true "Status payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /applications/status [put]
+func updateApplicationStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ID     int    `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if body.ID == 0 || !validStatus(body.Status) {
+		respondError(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	previous, err := changeStatus(body.ID, body.Status)
+	if err == sql.ErrNoRows {
+		respondError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err == errApplicationAnonymized {
+		respondError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			respondError(w, "Candidate already has an open application for this campaign", http.StatusConflict)
+			return
+		}
+		log.Printf("Error updating application status: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	auditChange(r, "applications", strconv.Itoa(body.ID),
+		map[string]string{"status": previous}, map[string]string{"status": body.Status})
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
@@ -0,0 +1,692 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Assignment methods
+const (
+	AssignManual     = "manual"
+	AssignRoundRobin = "round_robin"
+	AssignSubject    = "subject"
+)
+
+// assignmentStrategy is applied to new applications: none, round_robin, or
+// subject, which falls back to round_robin for subjects without an owner.
+var assignmentStrategy = getEnv("ASSIGNMENT_STRATEGY", AssignSubject)
+
+// workloadOpen holds for assigned applications "a" that still need work
+var workloadOpen = `a.status NOT IN ('` + strings.Join(append([]string{StatusAccepted}, closedStatuses...), `', '`) + `')`
+
+// Assignment is a reviewer in charge of an application
+type Assignment struct {
+	ReviewerID int     `json:"reviewer_id"`
+	Reviewer   string  `json:"reviewer"`
+	Method     string  `json:"method"`
+	AssignedBy *string `json:"assigned_by,omitempty"`
+	AssignedAt string  `json:"assigned_at"`
+}
+
+// AssignmentEvent is an entry of an application's assignment history
+type AssignmentEvent struct {
+	ID         int64   `json:"id"`
+	ReviewerID *int    `json:"reviewer_id,omitempty"`
+	Reviewer   *string `json:"reviewer,omitempty"`
+	Action     string  `json:"action"`
+	Method     string  `json:"method"`
+	Actor      *string `json:"actor,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// Reviewer is a back-office user with their automatic assignment settings
+type Reviewer struct {
+	UserID         int      `json:"user_id"`
+	Username       string   `json:"username"`
+	InPool         bool     `json:"in_pool"`
+	Active         bool     `json:"active"`
+	LastAssignedAt *string  `json:"last_assigned_at,omitempty"`
+	Subjects       []string `json:"subjects"`
+}
+
+// ReviewerWorkload counts the applications assigned to a reviewer. Pending
+// ones are open and not yet scored by the reviewer.
+type ReviewerWorkload struct {
+	ReviewerID    int     `json:"reviewer_id"`
+	Reviewer      string  `json:"reviewer"`
+	Active        bool    `json:"active"`
+	Total         int     `json:"total"`
+	Open          int     `json:"open"`
+	Pending       int     `json:"pending"`
+	Scored        int     `json:"scored"`
+	OldestPending *string `json:"oldest_pending,omitempty"`
+}
+
+func validAssignmentStrategy(s string) bool {
+	return s == AssignRoundRobin || s == AssignSubject
+}
+
+// assignReviewer adds a reviewer to an application and records it in the
+// history. It reports false if the reviewer was already assigned.
+func assignReviewer(tx *sql.Tx, appID, reviewerID int, method string, actorID *int) (bool, error) {
+	result, err := tx.Exec(`
+		INSERT INTO application_assignments (application_id, reviewer_id, method, assigned_by)
+		VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING
+	`, appID, reviewerID, method, actorID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO assignment_history (application_id, reviewer_id, action, method, actor_id)
+		VALUES ($1, $2, 'assigned', $3, $4)
+	`, appID, reviewerID, method, actorID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE reviewer_pool SET last_assigned_at=NOW() WHERE user_id=$1`, reviewerID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// autoAssign gives an application to the owners of its subjects (the least
+// loaded owner per subject) or, failing that, to the next reviewer of the
+// active pool in round-robin order. Inactive pool members are skipped.
+func autoAssign(tx *sql.Tx, appID int, strategy string) ([]int, error) {
+	if !validAssignmentStrategy(strategy) {
+		return nil, nil
+	}
+
+	// Serialise automatic assignment so concurrent submissions rotate
+	// through the pool instead of picking the same reviewer.
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('reviewer_assignment'))`); err != nil {
+		return nil, err
+	}
+
+	var reviewers []int
+	method := strategy
+	if strategy == AssignSubject {
+		rows, err := tx.Query(`
+			SELECT DISTINCT ON (aps.subject_id) so.user_id
+			FROM application_subjects aps
+			JOIN subject_owners so ON so.subject_id = aps.subject_id
+			LEFT JOIN reviewer_pool p ON p.user_id = so.user_id
+			WHERE aps.application_id=$1 AND COALESCE(p.active, TRUE)
+			ORDER BY aps.subject_id, (
+				SELECT COUNT(*) FROM application_assignments aa
+				JOIN applications a ON a.id = aa.application_id
+				WHERE aa.reviewer_id = so.user_id AND `+workloadOpen+`
+			), so.user_id
+		`, appID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			reviewers = append(reviewers, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(reviewers) == 0 {
+		method = AssignRoundRobin
+		var id int
+		err := tx.QueryRow(`
+			SELECT p.user_id FROM reviewer_pool p
+			WHERE p.active AND NOT EXISTS (
+				SELECT 1 FROM application_assignments aa
+				WHERE aa.application_id=$1 AND aa.reviewer_id = p.user_id
+			)
+			ORDER BY p.last_assigned_at NULLS FIRST, p.user_id
+			LIMIT 1
+		`, appID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, id)
+	}
+
+	var assigned []int
+	for _, id := range reviewers {
+		ok, err := assignReviewer(tx, appID, id, method, nil)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			assigned = append(assigned, id)
+		}
+	}
+	return assigned, nil
+}
+
+// assignmentsHandler godoc
+// @Summary Application reviewers
+// @Description Admin: the reviewers of an application and its assignment history (GET), assign reviewers (POST) or unassign one (DELETE)
+// @Tags Assignments
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param application_id query int false "Application ID (GET)"
+// @Param body body object{application_id=int,reviewer_ids=[]int,reviewer_id=int} false "POST {application_id, reviewer_ids}, DELETE {application_id, reviewer_id}"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /applications/assignments [get]
+// @Router /applications/assignments [post]
+// @Router /applications/assignments [delete]
+func assignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		appID, err := strconv.Atoi(r.URL.Query().Get("application_id"))
+		if err != nil {
+			respondError(w, "Invalid application_id", http.StatusBadRequest)
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT aa.reviewer_id, u.username, aa.method, b.username, aa.created_at
+			FROM application_assignments aa
+			JOIN users u ON u.id = aa.reviewer_id
+			LEFT JOIN users b ON b.id = aa.assigned_by
+			WHERE aa.application_id=$1
+			ORDER BY aa.created_at, u.username
+		`, appID)
+		if err != nil {
+			log.Printf("Error fetching assignments: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		assignments := []Assignment{}
+		for rows.Next() {
+			var a Assignment
+			var by sql.NullString
+			var at time.Time
+			if err := rows.Scan(&a.ReviewerID, &a.Reviewer, &a.Method, &by, &at); err != nil {
+				log.Printf("Error scanning assignment: %v", err)
+				continue
+			}
+			if by.Valid {
+				a.AssignedBy = &by.String
+			}
+			a.AssignedAt = at.Format(time.RFC3339)
+			assignments = append(assignments, a)
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT h.id, h.reviewer_id, u.username, h.action, h.method, act.username, h.created_at
+			FROM assignment_history h
+			LEFT JOIN users u ON u.id = h.reviewer_id
+			LEFT JOIN users act ON act.id = h.actor_id
+			WHERE h.application_id=$1
+			ORDER BY h.id
+		`, appID)
+		if err != nil {
+			log.Printf("Error fetching assignment history: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		history := []AssignmentEvent{}
+		for rows.Next() {
+			var e AssignmentEvent
+			var reviewerID sql.NullInt64
+			var reviewer, actor sql.NullString
+			var at time.Time
+			if err := rows.Scan(&e.ID, &reviewerID, &reviewer, &e.Action, &e.Method, &actor, &at); err != nil {
+				log.Printf("Error scanning assignment event: %v", err)
+				continue
+			}
+			if reviewerID.Valid {
+				id := int(reviewerID.Int64)
+				e.ReviewerID = &id
+				e.Reviewer = &reviewer.String
+			}
+			if actor.Valid {
+				e.Actor = &actor.String
+			}
+			e.CreatedAt = at.Format(time.RFC3339)
+			history = append(history, e)
+		}
+
+		respondJSON(w, map[string]interface{}{
+			"assignments": assignments,
+			"history":     history,
+		}, http.StatusOK)
+
+	case http.MethodPost, http.MethodDelete:
+		var body struct {
+			ApplicationID int   `json:"application_id"`
+			ReviewerIDs   []int `json:"reviewer_ids"`
+			ReviewerID    int   `json:"reviewer_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			body.ReviewerIDs = []int{body.ReviewerID}
+		}
+		if len(body.ReviewerIDs) == 0 {
+			respondError(w, "At least one reviewer is required", http.StatusBadRequest)
+			return
+		}
+
+		var actorID *int
+		if id, ok := currentUserID(r); ok {
+			actorID = &id
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		if err := tx.QueryRow(`SELECT id FROM applications WHERE id=$1 FOR UPDATE`, body.ApplicationID).Scan(&body.ApplicationID); err == sql.ErrNoRows {
+			respondError(w, "Application not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error fetching application: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			var valid bool
+			if err := tx.QueryRow(`
+				SELECT NOT EXISTS (
+					SELECT 1 FROM UNNEST($1::INT[]) AS r(id)
+					WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = r.id AND u.role = ANY($2))
+				)
+			`, pq.Array(body.ReviewerIDs), rolesWith(PermApplicationsReview)).Scan(&valid); err != nil {
+				log.Printf("Error checking reviewers: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if !valid {
+				respondError(w, "Unknown reviewer", http.StatusBadRequest)
+				return
+			}
+		}
+
+		changed := 0
+		for _, reviewerID := range body.ReviewerIDs {
+			var ok bool
+			if r.Method == http.MethodPost {
+				ok, err = assignReviewer(tx, body.ApplicationID, reviewerID, AssignManual, actorID)
+			} else {
+				var result sql.Result
+				result, err = tx.Exec(`
+					DELETE FROM application_assignments WHERE application_id=$1 AND reviewer_id=$2
+				`, body.ApplicationID, reviewerID)
+				if err == nil {
+					n, _ := result.RowsAffected()
+					if ok = n > 0; ok {
+						_, err = tx.Exec(`
+							INSERT INTO assignment_history (application_id, reviewer_id, action, method, actor_id)
+							VALUES ($1, $2, 'unassigned', $3, $4)
+						`, body.ApplicationID, reviewerID, AssignManual, actorID)
+					}
+				}
+			}
+			if err != nil {
+				if pqErr, isPq := err.(*pq.Error); isPq && pqErr.Code == "23503" {
+					respondError(w, "Unknown reviewer "+strconv.Itoa(reviewerID), http.StatusBadRequest)
+					return
+				}
+				log.Printf("Error updating assignment: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				changed++
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing assignment: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string]interface{}{"success": true, "changed": changed}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// autoAssignApplications godoc
+// @Summary Assign reviewers automatically
+// @Description Admin: assign reviewers by round_robin or subject ownership to the given applications, or to every open application without a reviewer
+// @Tags Assignments
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{strategy=string,application_ids=[]int} true "Assignment payload"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string
+// @Router /assignments/auto [post]
+func autoAssignApplications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Strategy       string `json:"strategy"`
+		ApplicationIDs []int  `json:"application_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !validAssignmentStrategy(body.Strategy) {
+		respondError(w, "Strategy must be round_robin or subject", http.StatusBadRequest)
+		return
+	}
+
+	if len(body.ApplicationIDs) == 0 {
+		rows, err := db.Query(`
+			SELECT a.id FROM applications a
+			WHERE ` + workloadOpen + ` AND NOT EXISTS (
+				SELECT 1 FROM application_assignments aa WHERE aa.application_id = a.id
+			)
+			ORDER BY a.created_at, a.id`)
+		if err != nil {
+			log.Printf("Error fetching unassigned applications: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				log.Printf("Error scanning application: %v", err)
+				continue
+			}
+			body.ApplicationIDs = append(body.ApplicationIDs, id)
+		}
+		rows.Close()
+	}
+
+	// One transaction per application keeps the rotation lock short
+	assigned := 0
+	for _, appID := range body.ApplicationIDs {
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		reviewers, err := autoAssign(tx, appID, body.Strategy)
+		if err == nil {
+			err = tx.Commit()
+		}
+		tx.Rollback()
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				continue
+			}
+			log.Printf("Error assigning application %d: %v", appID, err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if len(reviewers) > 0 {
+			assigned++
+		}
+	}
+	respondJSON(w, map[string]interface{}{"success": true, "assigned": assigned}, http.StatusOK)
+}
+
+// myQueue godoc
+// @Summary My review queue
+// @Description Admin: the open applications assigned to the current user, oldest first. Accepts the list endpoint filters; all=true includes settled applications.
+// @Tags Assignments
+// @Produce json
+// @Security SessionAuth
+// @Param all query bool false "Include accepted, rejected and withdrawn applications"
+// @Param status query string false "Status"
+// @Success 200 {array} ApplicationResponse
+// @Failure 400 {string} string
+// @Router /assignments/queue [get]
+func myQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+	params.Set("reviewer_id", strconv.Itoa(userID))
+	where, args, err := applicationFilters(params)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if params.Get("all") != "true" {
+		where += " AND " + workloadOpen
+	}
+	scope := scopeOf(r)
+	where, args = scope.filter(where, args)
+
+	result, err := queryApplications(`
+		SELECT `+applicationColumns+`
+		FROM applications a`+where+` ORDER BY a.created_at, a.id`, args...)
+	if err != nil {
+		log.Printf("Error fetching review queue: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	scope.redactAll(result)
+	if err := attachScores(result, userID); err != nil {
+		log.Printf("Error fetching application scores: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, result, http.StatusOK)
+}
+
+// reviewersHandler godoc
+// @Summary Reviewer pool and subject owners
+// @Description Admin: list users with their pool membership and owned subjects (GET), or set them for one user (PUT). Only active pool members take part in round-robin assignment; subject owners receive the applications for their subjects unless inactive. Owned subjects also bound what supervisors can see.
+// @Tags Assignments
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{user_id=int,in_pool=bool,active=bool,subjects=[]string} false "Reviewer settings (PUT)"
+// @Success 200 {array} Reviewer
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /reviewers [get]
+// @Router /reviewers [put]
+func reviewersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`
+			SELECT u.id, u.username, p.user_id IS NOT NULL, COALESCE(p.active, FALSE), p.last_assigned_at,
+			ARRAY(
+				SELECT s.name FROM subject_owners so
+				JOIN subjects s ON s.id = so.subject_id
+				WHERE so.user_id = u.id ORDER BY s.name
+			)
+			FROM users u
+			LEFT JOIN reviewer_pool p ON p.user_id = u.id
+			WHERE u.role = ANY($1)
+			ORDER BY u.username`, rolesWith(PermApplicationsReview))
+		if err != nil {
+			log.Printf("Error fetching reviewers: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		reviewers := []Reviewer{}
+		for rows.Next() {
+			var rv Reviewer
+			var last sql.NullTime
+			if err := rows.Scan(&rv.UserID, &rv.Username, &rv.InPool, &rv.Active, &last, pq.Array(&rv.Subjects)); err != nil {
+				log.Printf("Error scanning reviewer: %v", err)
+				continue
+			}
+			if last.Valid {
+				s := last.Time.Format(time.RFC3339)
+				rv.LastAssignedAt = &s
+			}
+			reviewers = append(reviewers, rv)
+		}
+		respondJSON(w, reviewers, http.StatusOK)
+
+	case http.MethodPut:
+		var body struct {
+			UserID   int      `json:"user_id"`
+			InPool   bool     `json:"in_pool"`
+			Active   bool     `json:"active"`
+			Subjects []string `json:"subjects"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var role string
+		if err := tx.QueryRow(`SELECT role FROM users WHERE id=$1`, body.UserID).Scan(&role); err == sql.ErrNoRows {
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error fetching user: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if (body.InPool || len(body.Subjects) > 0) && !roleHas(role, PermApplicationsReview) {
+			respondError(w, "User cannot review applications", http.StatusBadRequest)
+			return
+		}
+
+		if body.InPool {
+			_, err = tx.Exec(`
+				INSERT INTO reviewer_pool (user_id, active) VALUES ($1, $2)
+				ON CONFLICT (user_id) DO UPDATE SET active = EXCLUDED.active
+			`, body.UserID, body.Active)
+		} else {
+			_, err = tx.Exec(`DELETE FROM reviewer_pool WHERE user_id=$1`, body.UserID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM subject_owners WHERE user_id=$1`, body.UserID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT INTO subject_owners (subject_id, user_id)
+				SELECT id, $1 FROM subjects WHERE name = ANY($2)
+			`, body.UserID, pq.Array(body.Subjects))
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Error saving reviewer: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// reviewerWorkload godoc
+// @Summary Reviewer workload
+// @Description Admin: per reviewer, the assigned applications in total, still open, pending their scorecard and already scored, with the oldest pending assignment
+// @Tags Assignments
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {array} ReviewerWorkload
+// @Router /assignments/workload [get]
+func reviewerWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rows, err := db.Query(`
+		WITH assigned AS (
+			SELECT aa.reviewer_id, aa.created_at, ` + workloadOpen + ` AS open,
+			EXISTS (
+				SELECT 1 FROM scorecards c
+				WHERE c.application_id = aa.application_id AND c.reviewer_id = aa.reviewer_id
+				AND c.status = 'submitted'
+			) AS scored
+			FROM application_assignments aa
+			JOIN applications a ON a.id = aa.application_id
+		)
+		SELECT u.id, u.username, COALESCE(p.active, FALSE),
+		COUNT(x.reviewer_id),
+		COUNT(*) FILTER (WHERE x.open),
+		COUNT(*) FILTER (WHERE x.open AND NOT x.scored),
+		COUNT(*) FILTER (WHERE x.scored),
+		MIN(x.created_at) FILTER (WHERE x.open AND NOT x.scored)
+		FROM users u
+		LEFT JOIN reviewer_pool p ON p.user_id = u.id
+		LEFT JOIN assigned x ON x.reviewer_id = u.id
+		WHERE p.user_id IS NOT NULL OR x.reviewer_id IS NOT NULL
+		GROUP BY u.id, u.username, p.active
+		ORDER BY u.username`)
+	if err != nil {
+		log.Printf("Error computing workload: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	workload := []ReviewerWorkload{}
+	for rows.Next() {
+		var wl ReviewerWorkload
+		var oldest sql.NullTime
+		if err := rows.Scan(&wl.ReviewerID, &wl.Reviewer, &wl.Active, &wl.Total,
+			&wl.Open, &wl.Pending, &wl.Scored, &oldest); err != nil {
+			log.Printf("Error scanning workload: %v", err)
+			continue
+		}
+		if oldest.Valid {
+			s := oldest.Time.Format(time.RFC3339)
+			wl.OldestPending = &s
+		}
+		workload = append(workload, wl)
+	}
+	respondJSON(w, workload, http.StatusOK)
+}
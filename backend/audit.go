@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trustProxy makes the audit log take the client address from
+// X-Forwarded-For, for deployments behind a reverse proxy.
+var trustProxy = getEnv("TRUST_PROXY", "false") == "true"
+
+// auditBodyLimit bounds how much of a request body is read for the
+// identifiers it names.
+const auditBodyLimit = 64 << 10
+
+// AuditEntry is one line of the audit log. Hash covers every other field
+// and the hash of the previous entry, so editing or dropping a row breaks
+// the chain from there on.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	ActorID    *int            `json:"actor_id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before" swaggertype:"object"`
+	After      json.RawMessage `json:"after" swaggertype:"object"`
+	Status     int             `json:"status"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  string          `json:"created_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+
+	at time.Time
+}
+
+// digest hashes the entry fields in a fixed order. Each field is quoted so
+// no two entries can produce the same input.
+func (e AuditEntry) digest() string {
+	actorID := ""
+	if e.ActorID != nil {
+		actorID = strconv.Itoa(*e.ActorID)
+	}
+	h := sha256.New()
+	for _, f := range []string{
+		e.PrevHash, e.at.UTC().Format(time.RFC3339Nano), actorID, e.Actor,
+		e.Action, e.EntityType, e.EntityID, string(e.Before), string(e.After),
+		strconv.Itoa(e.Status), e.IP, e.UserAgent,
+	} {
+		io.WriteString(h, strconv.Quote(f))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditRecord collects what a handler reports about its change while the
+// request is served.
+type auditRecord struct {
+	action     string
+	entityType string
+	entityID   string
+	before     interface{}
+	after      interface{}
+}
+
+type auditKey struct{}
+
+// auditChange lets a handler running behind audited describe the entity it
+// changed and its state before and after. Nil states are left as recorded
+// by the middleware.
+func auditChange(r *http.Request, entityType, entityID string, before, after interface{}) {
+	rec, ok := r.Context().Value(auditKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	rec.entityType = entityType
+	rec.entityID = entityID
+	if before != nil {
+		rec.before = before
+	}
+	if after != nil {
+		rec.after = after
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// audited records every mutating request to next once it has been
+// answered. The audit log outlives erasure, so of a JSON body only the
+// identifiers are kept as the "after" state, unless the handler reports its
+// own through auditChange.
+func audited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		rec := &auditRecord{
+			action:     r.Method + " " + r.URL.Path,
+			entityType: strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 2)[0],
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			body, err := io.ReadAll(io.LimitReader(r.Body, auditBodyLimit+1))
+			if err == nil && len(body) <= auditBodyLimit {
+				var v interface{}
+				if json.Unmarshal(body, &v) == nil {
+					rec.after = auditIdentifiers(v)
+				}
+			}
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next(sw, r.WithContext(context.WithValue(r.Context(), auditKey{}, rec)))
+		recordAudit(r, sw.status, rec.action, rec.entityType, rec.entityID, rec.before, rec.after)
+	}
+}
+
+// auditIdentifiers keeps the top-level "id", "ids" and "*_id(s)" fields of
+// a JSON body when they hold numbers, and nil when there are none.
+func auditIdentifiers(v interface{}) interface{} {
+	body, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ids := map[string]interface{}{}
+	for k, val := range body {
+		if k != "id" && k != "ids" && !strings.HasSuffix(k, "_id") && !strings.HasSuffix(k, "_ids") {
+			continue
+		}
+		switch t := val.(type) {
+		case float64:
+			ids[k] = t
+		case []interface{}:
+			numbers := true
+			for _, item := range t {
+				if _, ok := item.(float64); !ok {
+					numbers = false
+					break
+				}
+			}
+			if numbers {
+				ids[k] = t
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids
+}
+
+func clientIP(r *http.Request) string {
+	if trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// newAuditEntry describes a request made by the session user of r
+func newAuditEntry(r *http.Request, status int, action, entityType, entityID string) AuditEntry {
+	e := AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Status:     status,
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+	if t := apiTokenOf(r); t != nil {
+		e.ActorID, e.Actor = &t.userID, t.username+" (token "+t.name+")"
+	} else if id, ok := currentUserID(r); ok {
+		e.ActorID = &id
+		if session, err := store.Get(r, "auth"); err == nil {
+			e.Actor, _ = session.Values["username"].(string)
+		}
+	}
+	return e
+}
+
+// recordAudit appends an entry for the session user of r. Failures are
+// logged, the request has been answered by then.
+func recordAudit(r *http.Request, status int, action, entityType, entityID string, before, after interface{}) {
+	if err := appendAudit(newAuditEntry(r, status, action, entityType, entityID), before, after); err != nil {
+		log.Printf("Error writing audit log (%s): %v", action, err)
+	}
+}
+
+// auditLogin records a login attempt. Failed attempts keep the username that
+// was tried, the session does not hold the user yet either way.
+func auditLogin(r *http.Request, userID *int, username string, status int) {
+	e := newAuditEntry(r, status, "login", "users", "")
+	e.ActorID, e.Actor = userID, username
+	if userID != nil {
+		e.EntityID = strconv.Itoa(*userID)
+	}
+	if err := appendAudit(e, nil, nil); err != nil {
+		log.Printf("Error writing audit log (login): %v", err)
+	}
+}
+
+// appendAudit chains e onto the last entry. The advisory lock keeps
+// concurrent writers from forking the chain.
+func appendAudit(e AuditEntry, before, after interface{}) error {
+	var err error
+	if before != nil {
+		if e.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if e.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('audit_log'))`); err != nil {
+		return err
+	}
+	err = tx.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&e.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// Postgres keeps microseconds, so the stored time hashes the same.
+	e.at = time.Now().UTC().Truncate(time.Microsecond)
+	e.Hash = e.digest()
+	if _, err := tx.Exec(`
+		INSERT INTO audit_log (actor_id, actor, action, entity_type, entity_id, before, after,
+			status, ip, user_agent, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, e.ActorID, e.Actor, e.Action, e.EntityType, e.EntityID, nullJSON(e.Before), nullJSON(e.After),
+		e.Status, e.IP, e.UserAgent, e.at, e.PrevHash, e.Hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+const auditColumns = `id, actor_id, actor, action, entity_type, entity_id, before, after,
+	status, ip, user_agent, created_at, prev_hash, hash`
+
+func scanAuditEntry(row rowScanner) (AuditEntry, error) {
+	var e AuditEntry
+	var actorID sql.NullInt64
+	var before, after sql.NullString
+	if err := row.Scan(&e.ID, &actorID, &e.Actor, &e.Action, &e.EntityType, &e.EntityID,
+		&before, &after, &e.Status, &e.IP, &e.UserAgent, &e.at, &e.PrevHash, &e.Hash); err != nil {
+		return e, err
+	}
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		e.ActorID = &id
+	}
+	if before.Valid {
+		e.Before = json.RawMessage(before.String)
+	}
+	if after.Valid {
+		e.After = json.RawMessage(after.String)
+	}
+	e.CreatedAt = e.at.UTC().Format(time.RFC3339Nano)
+	return e, nil
+}
+
+// parseAuditTime accepts a timestamp or a date
+func parseAuditTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// auditLog godoc
+// @Summary Audit log
+// @Description Users with audit:read: audit entries, newest first. Page with before_id.
+// @Tags Audit
+// @Produce json
+// @Security SessionAuth
+// @Param actor_id query int false "Actor user ID"
+// @Param actor query string false "Actor username"
+// @Param entity_type query string false "Entity type, e.g. applications"
+// @Param entity_id query string false "Entity ID"
+// @Param action query string false "Action"
+// @Param from query string false "On or after (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Before (RFC 3339, or YYYY-MM-DD inclusive)"
+// @Param before_id query int false "Only entries older than this ID"
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Success 200 {array} AuditEntry
+// @Failure 400 {string} string
+// @Router /audit [get]
+func auditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	for _, f := range []struct{ param, cond string }{
+		{"actor_id", "actor_id = ?"},
+		{"before_id", "id < ?"},
+	} {
+		if v := q.Get(f.param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				respondError(w, "Invalid "+f.param, http.StatusBadRequest)
+				return
+			}
+			add(f.cond, id)
+		}
+	}
+	for _, f := range []string{"actor", "entity_type", "entity_id", "action"} {
+		if v := q.Get(f); v != "" {
+			add(f+" = ?", v)
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := parseAuditTime(v)
+		if err != nil {
+			respondError(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+		add("created_at >= ?", t)
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := parseAuditTime(v)
+		if err != nil {
+			respondError(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+		if len(v) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1)
+		}
+		add("created_at < ?", t)
+	}
+
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondError(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if n > 1000 {
+			n = 1000
+		}
+		limit = n
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	rows, err := db.Query(query+` ORDER BY id DESC LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		log.Printf("Error fetching audit log: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			log.Printf("Error scanning audit entry: %v", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	respondJSON(w, entries, http.StatusOK)
+}
+
+var errAuditChainBroken = errors.New("audit chain broken")
+
+// verifyAuditChain walks the whole log and returns the ID of the first entry
+// whose hash or link does not match, with errAuditChainBroken.
+func verifyAuditChain() (int, int64, error) {
+	rows, err := db.Query(`SELECT ` + auditColumns + ` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	prev := ""
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return n, 0, err
+		}
+		if e.PrevHash != prev || e.digest() != e.Hash {
+			return n, e.ID, errAuditChainBroken
+		}
+		prev = e.Hash
+		n++
+	}
+	return n, 0, rows.Err()
+}
+
+// verifyAuditLog godoc
+// @Summary Verify the audit log
+// @Description Users with audit:read: recompute the hash chain and report the first entry that was altered, or follows a removed one
+// @Tags Audit
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /audit/verify [get]
+func verifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n, brokenAt, err := verifyAuditChain()
+	if err == errAuditChainBroken {
+		respondJSON(w, map[string]interface{}{
+			"valid":     false,
+			"verified":  n,
+			"broken_at": brokenAt,
+		}, http.StatusOK)
+		return
+	} else if err != nil {
+		log.Printf("Error verifying audit log: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]interface{}{
+		"valid":    true,
+		"verified": n,
+	}, http.StatusOK)
+}
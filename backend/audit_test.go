@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// auditChain links entries the way appendAudit does
+func auditChain(entries []AuditEntry) []AuditEntry {
+	prev := ""
+	for i := range entries {
+		entries[i].ID = int64(i + 1)
+		entries[i].PrevHash = prev
+		entries[i].Hash = entries[i].digest()
+		prev = entries[i].Hash
+	}
+	return entries
+}
+
+// firstBroken checks entries like verifyAuditChain, returning the ID of the
+// first one that does not match, or 0.
+func firstBroken(entries []AuditEntry) int64 {
+	prev := ""
+	for _, e := range entries {
+		if e.PrevHash != prev || e.digest() != e.Hash {
+			return e.ID
+		}
+		prev = e.Hash
+	}
+	return 0
+}
+
+func TestAuditEntryDigest(t *testing.T) {
+	actor := 1
+	other := 2
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+	newChain := func() []AuditEntry {
+		var entries []AuditEntry
+		for i := 0; i < 3; i++ {
+			entries = append(entries, AuditEntry{
+				ActorID:    &actor,
+				Actor:      "admin",
+				Action:     "PUT /applications/status",
+				EntityType: "applications",
+				EntityID:   "42",
+				Before:     json.RawMessage(`{"status":"submitted"}`),
+				After:      json.RawMessage(`{"status":"accepted"}`),
+				Status:     200,
+				IP:         "192.0.2.1",
+				UserAgent:  "test",
+				at:         at.Add(time.Duration(i) * time.Second),
+			})
+		}
+		return auditChain(entries)
+	}
+
+	if id := firstBroken(newChain()); id != 0 {
+		t.Fatalf("intact chain broken at %d", id)
+	}
+
+	edits := []struct {
+		name string
+		edit func(e *AuditEntry)
+	}{
+		{"actor id", func(e *AuditEntry) { e.ActorID = &other }},
+		{"actor id removed", func(e *AuditEntry) { e.ActorID = nil }},
+		{"actor", func(e *AuditEntry) { e.Actor = "someone" }},
+		{"action", func(e *AuditEntry) { e.Action = "DELETE /applications" }},
+		{"entity type", func(e *AuditEntry) { e.EntityType = "candidates" }},
+		{"entity id", func(e *AuditEntry) { e.EntityID = "43" }},
+		{"before", func(e *AuditEntry) { e.Before = json.RawMessage(`{"status":"rejected"}`) }},
+		{"after", func(e *AuditEntry) { e.After = nil }},
+		{"status", func(e *AuditEntry) { e.Status = 403 }},
+		{"ip", func(e *AuditEntry) { e.IP = "198.51.100.1" }},
+		{"user agent", func(e *AuditEntry) { e.UserAgent = "other" }},
+		{"time", func(e *AuditEntry) { e.at = e.at.Add(time.Microsecond) }},
+		{"shifted field boundary", func(e *AuditEntry) { e.Actor, e.Action = "adminPUT", " /applications/status" }},
+	}
+	for _, tt := range edits {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := newChain()
+			tt.edit(&entries[1])
+			if id := firstBroken(entries); id != 2 {
+				t.Errorf("chain broken at %d, want 2", id)
+			}
+		})
+	}
+
+	t.Run("rehashed edit", func(t *testing.T) {
+		entries := newChain()
+		entries[1].Status = 403
+		entries[1].Hash = entries[1].digest()
+		if id := firstBroken(entries); id != 3 {
+			t.Errorf("chain broken at %d, want 3", id)
+		}
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		entries := newChain()
+		entries = append(entries[:1], entries[2:]...)
+		if id := firstBroken(entries); id != 3 {
+			t.Errorf("chain broken at %d, want 3", id)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Campaign is a recruitment window candidates apply to
+type Campaign struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	OpensOn  string  `json:"opens_on"`
+	ClosesOn *string `json:"closes_on,omitempty"`
+	IsOpen   bool    `json:"is_open"`
+	RubricID *int    `json:"rubric_id,omitempty"`
+}
+
+const campaignOpenCondition = `opens_on <= CURRENT_DATE AND (closes_on IS NULL OR closes_on >= CURRENT_DATE)`
+
+func queryCampaigns(where string, args ...interface{}) ([]Campaign, error) {
+	query := `SELECT id, name, opens_on, closes_on, ` + campaignOpenCondition + `, rubric_id FROM campaigns`
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := db.Query(query+" ORDER BY opens_on DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []Campaign{}
+	for rows.Next() {
+		var c Campaign
+		var opens time.Time
+		var closes sql.NullTime
+		var rubricID sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.Name, &opens, &closes, &c.IsOpen, &rubricID); err != nil {
+			return nil, err
+		}
+		c.OpensOn = opens.Format("2006-01-02")
+		if closes.Valid {
+			s := closes.Time.Format("2006-01-02")
+			c.ClosesOn = &s
+		}
+		if rubricID.Valid {
+			id := int(rubricID.Int64)
+			c.RubricID = &id
+		}
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, rows.Err()
+}
+
+// openCampaigns godoc
+// @Summary List open campaigns
+// @Description Campaigns currently accepting applications
+// @Tags Applications
+// @Produce json
+// @Success 200 {array} Campaign
+// @Router /campaigns [get]
+func openCampaigns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	campaigns, err := queryCampaigns(campaignOpenCondition)
+	if err != nil {
+		log.Printf("Error fetching campaigns: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, campaigns, http.StatusOK)
+}
+
+// manageCampaigns godoc
+// @Summary Manage campaigns
+// @Description Admin: list all campaigns, create or update one. rubric_id selects the scoring rubric, otherwise the default rubric applies.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int,name=string,opens_on=string,closes_on=string,rubric_id=int} false "Campaign payload (POST/PUT)"
+// @Success 200 {array} Campaign
+// @Failure 400 {string} string
+// @Failure 409 {string} string
+// @Router /campaigns/manage [get]
+// @Router /campaigns/manage [post]
+// @Router /campaigns/manage [put]
+func manageCampaigns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		campaigns, err := queryCampaigns("")
+		if err != nil {
+			log.Printf("Error fetching campaigns: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, campaigns, http.StatusOK)
+
+	case http.MethodPost, http.MethodPut:
+		var body struct {
+			ID       int    `json:"id"`
+			Name     string `json:"name"`
+			OpensOn  string `json:"opens_on"`
+			ClosesOn string `json:"closes_on"`
+			RubricID *int   `json:"rubric_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if body.Name == "" || body.OpensOn == "" || (r.Method == http.MethodPut && body.ID == 0) {
+			respondError(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		opens, err := time.Parse("2006-01-02", body.OpensOn)
+		if err != nil {
+			respondError(w, "Invalid opens_on date", http.StatusBadRequest)
+			return
+		}
+		var closes sql.NullTime
+		if body.ClosesOn != "" {
+			t, err := time.Parse("2006-01-02", body.ClosesOn)
+			if err != nil || t.Before(opens) {
+				respondError(w, "Invalid closes_on date", http.StatusBadRequest)
+				return
+			}
+			closes = sql.NullTime{Time: t, Valid: true}
+		}
+
+		if r.Method == http.MethodPost {
+			var id int
+			err = db.QueryRow(`
+				INSERT INTO campaigns (name, opens_on, closes_on, rubric_id)
+				VALUES ($1, $2, $3, $4) RETURNING id
+			`, body.Name, opens, closes, body.RubricID).Scan(&id)
+			body.ID = id
+		} else {
+			var res sql.Result
+			res, err = db.Exec(`
+				UPDATE campaigns SET name=$1, opens_on=$2, closes_on=$3, rubric_id=$4 WHERE id=$5
+			`, body.Name, opens, closes, body.RubricID, body.ID)
+			if err == nil {
+				if n, _ := res.RowsAffected(); n == 0 {
+					respondError(w, "Campaign not found", http.StatusNotFound)
+					return
+				}
+			}
+		}
+
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				respondError(w, "Campaign already exists", http.StatusConflict)
+				return
+			}
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				respondError(w, "Unknown rubric", http.StatusBadRequest)
+				return
+			}
+			log.Printf("Error saving campaign: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		code := http.StatusOK
+		if r.Method == http.MethodPost {
+			code = http.StatusCreated
+		}
+		respondJSON(w, map[string]interface{}{"success": true, "id": body.ID}, code)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Candidate links every application submitted with the same email
+type Candidate struct {
+	ID               int    `json:"id"`
+	Email            string `json:"email"`
+	FullName         string `json:"full_name"`
+	CreatedAt        string `json:"created_at"`
+	ApplicationCount int    `json:"application_count"`
+	LastAppliedAt    string `json:"last_applied_at"`
+}
+
+// CandidateHistory is a candidate with all of their applications, newest first
+type CandidateHistory struct {
+	Candidate
+	Applications []ApplicationResponse `json:"applications"`
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+const candidateColumns = `
+	c.id, c.email, c.full_name, c.created_at,
+	(SELECT COUNT(*) FROM applications a WHERE a.candidate_id = c.id),
+	(SELECT MAX(a.created_at) FROM applications a WHERE a.candidate_id = c.id)`
+
+// candidateColumns is like the constant of the same name, counting only the
+// applications within the scope. The supervisor is bound to placeholder.
+func (s applicationScope) candidateColumns(placeholder string) string {
+	if !s.restricted() {
+		return candidateColumns
+	}
+	cond := strings.ReplaceAll(supervisedCondition, "?", placeholder)
+	return `
+	c.id, c.email, c.full_name, c.created_at,
+	(SELECT COUNT(*) FROM applications a WHERE a.candidate_id = c.id AND ` + cond + `),
+	(SELECT MAX(a.created_at) FROM applications a WHERE a.candidate_id = c.id AND ` + cond + `)`
+}
+
+func scanCandidate(row rowScanner) (Candidate, error) {
+	var c Candidate
+	var created time.Time
+	var last sql.NullTime
+	if err := row.Scan(&c.ID, &c.Email, &c.FullName, &created, &c.ApplicationCount, &last); err != nil {
+		return c, err
+	}
+	c.CreatedAt = created.Format("2006-01-02")
+	if last.Valid {
+		c.LastAppliedAt = last.Time.Format("2006-01-02")
+	}
+	return c, nil
+}
+
+// listCandidates godoc
+// @Summary List candidates
+// @Description Admin: list candidate identities with their application counts. Supervisors only see candidates for the subjects they own
+// @Tags Admin
+// @Produce json
+// @Security SessionAuth
+// @Param q query string false "Filter by email or name"
+// @Success 200 {array} Candidate
+// @Failure 403 {string} string
+// @Router /candidates [get]
+func listCandidates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var conds []string
+	var args []interface{}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		args = append(args, "%"+q+"%")
+		conds = append(conds, `(c.email ILIKE $1 OR c.full_name ILIKE $1)`)
+	}
+	columns := candidateColumns
+	if scope := scopeOf(r); scope.restricted() {
+		args = append(args, scope.supervisorID)
+		placeholder := "$" + strconv.Itoa(len(args))
+		conds = append(conds, `EXISTS (SELECT 1 FROM applications a WHERE a.candidate_id = c.id AND `+
+			strings.ReplaceAll(supervisedCondition, "?", placeholder)+`)`)
+		columns = scope.candidateColumns(placeholder)
+	}
+
+	query := `SELECT ` + columns + ` FROM candidates c`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	rows, err := db.Query(query+` ORDER BY c.updated_at DESC`, args...)
+	if err != nil {
+		log.Printf("Error fetching candidates: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	candidates := []Candidate{}
+	for rows.Next() {
+		c, err := scanCandidate(rows)
+		if err != nil {
+			log.Printf("Error scanning candidate: %v", err)
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	respondJSON(w, candidates, http.StatusOK)
+}
+
+// candidateApplications godoc
+// @Summary Candidate application history
+// @Description Admin: a candidate and every application they submitted, looked up by id or email. Supervisors only get the applications for the subjects they own
+// @Tags Admin
+// @Produce json
+// @Security SessionAuth
+// @Param id query int false "Candidate ID"
+// @Param email query string false "Candidate email"
+// @Success 200 {object} CandidateHistory
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /candidates/applications [get]
+func candidateApplications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scope := scopeOf(r)
+	columns, args := scope.candidateColumns("$2"), []interface{}{}
+	if scope.restricted() {
+		args = append(args, scope.supervisorID)
+	}
+
+	var row *sql.Row
+	if v := r.URL.Query().Get("id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, "Invalid candidate id", http.StatusBadRequest)
+			return
+		}
+		row = db.QueryRow(`SELECT `+columns+` FROM candidates c WHERE c.id=$1`, append([]interface{}{id}, args...)...)
+	} else if email := normalizeEmail(r.URL.Query().Get("email")); email != "" {
+		row = db.QueryRow(`SELECT `+columns+` FROM candidates c WHERE c.email=$1`, append([]interface{}{email}, args...)...)
+	} else {
+		respondError(w, "id or email parameter required", http.StatusBadRequest)
+		return
+	}
+
+	c, err := scanCandidate(row)
+	if err == sql.ErrNoRows {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	where, args := scope.filter(" WHERE a.candidate_id=$1", []interface{}{c.ID})
+	apps, err := queryApplications(`
+		SELECT `+applicationColumns+`
+		FROM applications a`+where+`
+		ORDER BY a.created_at DESC`, args...)
+	if err != nil {
+		log.Printf("Error fetching candidate applications: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if scope.restricted() && len(apps) == 0 {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	}
+	scope.redactAll(apps)
+	respondJSON(w, CandidateHistory{Candidate: c, Applications: apps}, http.StatusOK)
+}
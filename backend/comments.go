@@ -0,0 +1,483 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Comment visibilities: team notes are shown to every reviewer, private notes
+// only to their author.
+const (
+	VisibilityTeam    = "team"
+	VisibilityPrivate = "private"
+)
+
+const commentMaxLength = 10000
+
+// mentionPattern matches @username not preceded by a word character, so
+// email addresses in a note are not taken as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@(\w[\w.-]*)`)
+
+// Comment is a note on an application with its replies
+type Comment struct {
+	ID            int       `json:"id"`
+	ApplicationID int       `json:"application_id"`
+	ParentID      *int      `json:"parent_id,omitempty"`
+	AuthorID      int       `json:"author_id"`
+	Author        string    `json:"author"`
+	Body          string    `json:"body"`
+	Visibility    string    `json:"visibility"`
+	Mentions      []string  `json:"mentions"`
+	Edited        bool      `json:"edited"`
+	Deleted       bool      `json:"deleted"`
+	CreatedAt     string    `json:"created_at"`
+	UpdatedAt     string    `json:"updated_at"`
+	Replies       []Comment `json:"replies"`
+}
+
+// CommentRevision is an earlier version of an edited comment
+type CommentRevision struct {
+	Body     string `json:"body"`
+	EditedAt string `json:"edited_at"`
+}
+
+// commentColumns matches the scan order of scanComment over comments "c"
+// joined with their author "u"
+const commentColumns = `c.id, c.application_id, c.parent_id, c.author_id, u.username,
+	c.body, c.visibility, c.created_at, c.updated_at, c.deleted_at IS NOT NULL,
+	EXISTS (SELECT 1 FROM comment_revisions r WHERE r.comment_id = c.id),
+	ARRAY(
+		SELECT mu.username FROM comment_mentions m
+		JOIN users mu ON mu.id = m.user_id
+		WHERE m.comment_id = c.id ORDER BY mu.username
+	)`
+
+// commentVisible restricts comments "c" to those user $1 may read
+const commentVisible = `(c.visibility = 'team' OR c.author_id = $1)`
+
+func scanComment(row rowScanner) (Comment, error) {
+	var c Comment
+	var parentID sql.NullInt64
+	var created, updated time.Time
+	err := row.Scan(&c.ID, &c.ApplicationID, &parentID, &c.AuthorID, &c.Author,
+		&c.Body, &c.Visibility, &created, &updated, &c.Deleted, &c.Edited, pq.Array(&c.Mentions))
+	if err != nil {
+		return c, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	c.CreatedAt = created.Format(time.RFC3339)
+	c.UpdatedAt = updated.Format(time.RFC3339)
+	c.Replies = []Comment{}
+	return c, nil
+}
+
+// commentThreads nests comments under their parents. The input is in
+// creation order, so replies keep that order too.
+func commentThreads(comments []Comment) []Comment {
+	children := map[int][]Comment{}
+	for _, c := range comments {
+		parent := 0
+		if c.ParentID != nil {
+			parent = *c.ParentID
+		}
+		children[parent] = append(children[parent], c)
+	}
+
+	var build func(parent int) []Comment
+	build = func(parent int) []Comment {
+		list := []Comment{}
+		for _, c := range children[parent] {
+			c.Replies = build(c.ID)
+			list = append(list, c)
+		}
+		return list
+	}
+	return build(0)
+}
+
+// mentionedNames extracts the distinct, lower-cased usernames mentioned in a body
+func mentionedNames(body string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// saveMentions records the team members mentioned in a team comment and emails
+// those who were not mentioned in it before. Private notes mention nobody,
+// since nobody else can read them.
+func saveMentions(tx *sql.Tx, c Comment, fullName string) error {
+	var names []string
+	if c.Visibility == VisibilityTeam && !c.Deleted {
+		names = mentionedNames(c.Body)
+	}
+
+	// Supervisors are only told about applications for their own subjects
+	rows, err := tx.Query(`
+		SELECT u.id, u.username, u.email FROM users u
+		WHERE LOWER(u.username) = ANY($1) AND u.id <> $2 AND u.role = ANY($3)
+			AND (u.role <> $4 OR EXISTS (SELECT 1 FROM applications a WHERE a.id = $5 AND `+
+		strings.ReplaceAll(supervisedCondition, "?", "u.id")+`))
+	`, pq.Array(names), c.AuthorID, rolesWith(PermApplicationsRead), RoleSupervisor, c.ApplicationID)
+	if err != nil {
+		return err
+	}
+	type mentioned struct {
+		id              int
+		username, email string
+	}
+	var users []mentioned
+	ids := []int{}
+	for rows.Next() {
+		var m mentioned
+		if err := rows.Scan(&m.id, &m.username, &m.email); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, m)
+		ids = append(ids, m.id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM comment_mentions WHERE comment_id=$1 AND NOT (user_id = ANY($2))
+	`, c.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	excerpt := c.Body
+	if runes := []rune(excerpt); len(runes) > 500 {
+		excerpt = string(runes[:500]) + "…"
+	}
+	for _, m := range users {
+		result, err := tx.Exec(`
+			INSERT INTO comment_mentions (comment_id, user_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, c.ID, m.id)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		if err := enqueueEmail(tx, m.email, "mention", map[string]interface{}{
+			"Username":      m.username,
+			"Author":        c.Author,
+			"ApplicationID": c.ApplicationID,
+			"FullName":      fullName,
+			"Excerpt":       excerpt,
+			"BackofficeURL": appURL,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadComment(q queryer, id, viewerID int) (Comment, error) {
+	return scanComment(q.QueryRow(`
+		SELECT `+commentColumns+`
+		FROM comments c JOIN users u ON u.id = c.author_id
+		WHERE `+commentVisible+` AND c.id=$2`, viewerID, id))
+}
+
+// commentsHandler godoc
+// @Summary Application comments
+// @Description Reviewers: threaded notes on an application. GET lists the threads you can see, POST adds a comment or reply, PUT edits your comment keeping the previous text in its history, DELETE removes the text of your comment. @username mentions in team notes email the mentioned user if they can read applications.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param application_id query int false "Application ID (GET)"
+// @Param body body object{id=int,application_id=int,parent_id=int,body=string,visibility=string} false "Comment payload: POST {application_id, parent_id, body, visibility}, PUT {id, body}, DELETE {id}"
+// @Success 200 {array} Comment
+// @Success 201 {object} Comment
+// @Failure 400 {string} string
+// @Failure 403 {string} string
+// @Failure 404 {string} string
+// @Router /applications/comments [get]
+// @Router /applications/comments [post]
+// @Router /applications/comments [put]
+// @Router /applications/comments [delete]
+func commentsHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, ok := currentUserID(r)
+	if !ok {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		appID, err := strconv.Atoi(r.URL.Query().Get("application_id"))
+		if err != nil {
+			respondError(w, "Invalid application_id", http.StatusBadRequest)
+			return
+		}
+		if !requireApplication(w, r, appID) {
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT `+commentColumns+`
+			FROM comments c JOIN users u ON u.id = c.author_id
+			WHERE `+commentVisible+` AND c.application_id=$2
+			ORDER BY c.created_at, c.id
+		`, viewerID, appID)
+		if err != nil {
+			log.Printf("Error fetching comments: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		var comments []Comment
+		for rows.Next() {
+			c, err := scanComment(rows)
+			if err != nil {
+				log.Printf("Error scanning comment: %v", err)
+				continue
+			}
+			comments = append(comments, c)
+		}
+		respondJSON(w, commentThreads(comments), http.StatusOK)
+		return
+	}
+
+	var body struct {
+		ID            int    `json:"id"`
+		ApplicationID int    `json:"application_id"`
+		ParentID      *int   `json:"parent_id"`
+		Body          string `json:"body"`
+		Visibility    string `json:"visibility"`
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPost && !requireApplication(w, r, body.ApplicationID) {
+		return
+	}
+	body.Body = strings.TrimSpace(body.Body)
+	if r.Method != http.MethodDelete && (body.Body == "" || len(body.Body) > commentMaxLength) {
+		respondError(w, "Comment must be between 1 and "+strconv.Itoa(commentMaxLength)+" characters", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	code := http.StatusOK
+	if r.Method == http.MethodPost {
+		if body.Visibility == "" {
+			body.Visibility = VisibilityTeam
+		}
+		if body.Visibility != VisibilityTeam && body.Visibility != VisibilityPrivate {
+			respondError(w, "Visibility must be team or private", http.StatusBadRequest)
+			return
+		}
+
+		if body.ParentID != nil {
+			// Replies stay in the parent's thread and are never more
+			// visible than the comment they answer.
+			parent, err := loadComment(tx, *body.ParentID, viewerID)
+			if err == sql.ErrNoRows || (err == nil && parent.ApplicationID != body.ApplicationID) {
+				respondError(w, "Parent comment not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				log.Printf("Error fetching parent comment: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if parent.Deleted {
+				respondError(w, "Cannot reply to a deleted comment", http.StatusConflict)
+				return
+			}
+			if parent.Visibility == VisibilityPrivate {
+				body.Visibility = VisibilityPrivate
+			}
+		}
+
+		err = tx.QueryRow(`
+			INSERT INTO comments (application_id, parent_id, author_id, body, visibility)
+			SELECT a.id, $2, $3, $4, $5 FROM applications a WHERE a.id=$1
+			RETURNING id
+		`, body.ApplicationID, body.ParentID, viewerID, body.Body, body.Visibility).Scan(&body.ID)
+		if err == sql.ErrNoRows {
+			respondError(w, "Application not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error creating comment: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		code = http.StatusCreated
+	} else {
+		var authorID int
+		var previous string
+		var deleted bool
+		err := tx.QueryRow(`
+			SELECT c.author_id, c.body, c.deleted_at IS NOT NULL FROM comments c
+			WHERE `+commentVisible+` AND c.id=$2 FOR UPDATE
+		`, viewerID, body.ID).Scan(&authorID, &previous, &deleted)
+		if err == sql.ErrNoRows {
+			respondError(w, "Comment not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error fetching comment: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if authorID != viewerID {
+			respondError(w, "Only the author can change a comment", http.StatusForbidden)
+			return
+		}
+		if deleted {
+			respondError(w, "Comment is deleted", http.StatusConflict)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			if previous != body.Body {
+				if _, err = tx.Exec(`
+					INSERT INTO comment_revisions (comment_id, body) VALUES ($1, $2)
+				`, body.ID, previous); err == nil {
+					_, err = tx.Exec(`UPDATE comments SET body=$1, updated_at=NOW() WHERE id=$2`, body.Body, body.ID)
+				}
+			}
+		} else {
+			// The thread keeps its place but the text and its history go
+			if _, err = tx.Exec(`DELETE FROM comment_revisions WHERE comment_id=$1`, body.ID); err == nil {
+				_, err = tx.Exec(`
+					UPDATE comments SET body='', deleted_at=NOW(), updated_at=NOW() WHERE id=$1
+				`, body.ID)
+			}
+		}
+		if err != nil {
+			log.Printf("Error updating comment: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c, err := loadComment(tx, body.ID, viewerID)
+	if err != nil {
+		log.Printf("Error fetching comment: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var fullName string
+	if err := tx.QueryRow(`SELECT full_name FROM applications WHERE id=$1`, c.ApplicationID).Scan(&fullName); err != nil {
+		log.Printf("Error fetching application: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := saveMentions(tx, c, fullName); err != nil {
+		log.Printf("Error saving mentions: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if c, err = loadComment(tx, body.ID, viewerID); err != nil {
+		log.Printf("Error fetching comment: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing comment: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, c, code)
+}
+
+// commentHistory godoc
+// @Summary Comment edit history
+// @Description Admin: earlier versions of a comment you can see, oldest first
+// @Tags Comments
+// @Produce json
+// @Security SessionAuth
+// @Param id query int true "Comment ID"
+// @Success 200 {array} CommentRevision
+// @Failure 404 {string} string
+// @Router /applications/comments/history [get]
+func commentHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	viewerID, ok := currentUserID(r)
+	if !ok {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		respondError(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	c, err := loadComment(db, id, viewerID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Comment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching comment: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !requireApplication(w, r, c.ApplicationID) {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT body, edited_at FROM comment_revisions WHERE comment_id=$1 ORDER BY edited_at, id
+	`, id)
+	if err != nil {
+		log.Printf("Error fetching comment history: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	revisions := []CommentRevision{}
+	for rows.Next() {
+		var rev CommentRevision
+		var edited time.Time
+		if err := rows.Scan(&rev.Body, &edited); err != nil {
+			log.Printf("Error scanning comment revision: %v", err)
+			continue
+		}
+		rev.EditedAt = edited.Format(time.RFC3339)
+		revisions = append(revisions, rev)
+	}
+	respondJSON(w, revisions, http.StatusOK)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PrivacyNotice is one published version of the privacy notice. Versions
+// are never edited: a change publishes a new one, which applicants must
+// then accept.
+type PrivacyNotice struct {
+	Version     int    `json:"version"`
+	Body        string `json:"body"`
+	PublishedBy string `json:"published_by,omitempty"`
+	PublishedAt string `json:"published_at"`
+}
+
+const noticeColumns = `n.version, n.body, COALESCE(u.username, ''), n.published_at
+	FROM privacy_notices n LEFT JOIN users u ON u.id = n.published_by`
+
+func scanNotice(row rowScanner) (PrivacyNotice, error) {
+	var n PrivacyNotice
+	var published time.Time
+	if err := row.Scan(&n.Version, &n.Body, &n.PublishedBy, &published); err != nil {
+		return n, err
+	}
+	n.PublishedAt = published.Format(time.RFC3339)
+	return n, nil
+}
+
+// currentNotice is the latest published version
+func currentNotice(q queryer) (PrivacyNotice, error) {
+	return scanNotice(q.QueryRow(`SELECT ` + noticeColumns + ` ORDER BY n.version DESC LIMIT 1`))
+}
+
+// consentGiven reads a form checkbox
+func consentGiven(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "on", "1", "yes":
+		return true
+	}
+	return false
+}
+
+// privacyNotice godoc
+// @Summary Current privacy notice
+// @Description The privacy notice applicants accept when they apply. Its version goes in the consent_version field of /apply.
+// @Tags Applications
+// @Produce json
+// @Success 200 {object} PrivacyNotice
+// @Router /privacy-notice [get]
+func privacyNotice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n, err := currentNotice(db)
+	if err == sql.ErrNoRows {
+		respondError(w, "No privacy notice published", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching privacy notice: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	n.PublishedBy = ""
+	respondJSON(w, n, http.StatusOK)
+}
+
+// privacyNotices godoc
+// @Summary Manage the privacy notice
+// @Description Users with privacy:manage: every published version, newest first (GET), or publish a new version (POST). New applications must accept the latest version.
+// @Tags Privacy
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{body=string} false "Notice payload (POST)"
+// @Success 200 {array} PrivacyNotice
+// @Failure 400 {string} string
+// @Router /privacy/notices [get]
+// @Router /privacy/notices [post]
+func privacyNotices(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`SELECT ` + noticeColumns + ` ORDER BY n.version DESC`)
+		if err != nil {
+			log.Printf("Error fetching privacy notices: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		notices := []PrivacyNotice{}
+		for rows.Next() {
+			n, err := scanNotice(rows)
+			if err != nil {
+				log.Printf("Error scanning privacy notice: %v", err)
+				continue
+			}
+			notices = append(notices, n)
+		}
+		respondJSON(w, notices, http.StatusOK)
+
+	case http.MethodPost:
+		var body struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		body.Body = strings.TrimSpace(body.Body)
+		if body.Body == "" {
+			respondError(w, "Notice text required", http.StatusBadRequest)
+			return
+		}
+
+		var publishedBy *int
+		if id, ok := currentUserID(r); ok {
+			publishedBy = &id
+		}
+		var version int
+		if err := db.QueryRow(`
+			INSERT INTO privacy_notices (body, published_by) VALUES ($1, $2) RETURNING version
+		`, body.Body, publishedBy).Scan(&version); err != nil {
+			log.Printf("Error publishing privacy notice: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string]interface{}{"success": true, "version": version}, http.StatusCreated)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// updateConsent godoc
+// @Summary Change my optional consent
+// @Description Applicant: give or withdraw consent to keep the signed-in candidate's profile for future campaigns, on all their applications
+// @Tags Applicant
+// @Accept json
+// @Produce json
+// @Param body body object{keep_profile=bool} true "Consent payload"
+// @Success 200 {object} map[string]bool
+// @Failure 401 {string} string
+// @Router /applicant/consent [put]
+func updateConsent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		KeepProfile bool `json:"keep_profile"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	candidateID, _ := currentCandidate(r)
+	if _, err := db.Exec(`
+		UPDATE applications SET keep_profile=$1 WHERE candidate_id=$2 AND anonymized_at IS NULL
+	`, body.KeepProfile, candidateID); err != nil {
+		log.Printf("Error updating consent: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// csrfHeader carries the token of the "csrf" session on every request that
+// changes something. A page on another site can make the browser send our
+// cookies, but cannot read the token to send along with them.
+const csrfHeader = "X-CSRF-Token"
+
+// frontendOrigin is the only other origin allowed to call the API with
+// credentials
+var frontendOrigin = getEnv("FRONTEND_ORIGIN", "http://localhost:3000")
+
+// csrfToken returns the token of the browser's "csrf" session, creating the
+// session on first use. Unlike the sign-in, the cookie lasts until the
+// browser closes, so open tabs keep a valid token.
+func csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, _ := store.Get(r, "csrf")
+	if token, ok := session.Values["token"].(string); ok && token != "" {
+		return token, nil
+	}
+	token, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	session.Values["token"] = token
+	session.Options = &sessions.Options{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode}
+	return token, session.Save(r, w)
+}
+
+// sameOrigin reports whether origin, a scheme://host[:port] or a full URL,
+// is the frontend or the API itself.
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme+"://"+u.Host == frontendOrigin {
+		return true
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// checkCSRF rejects state-changing requests that come from another site.
+// Browsers name the origin in Origin, or failing that in Referer; requests
+// without either come from scripts, which still need the token.
+//
+// Browsers never attach an API token on their own, so requests carrying one
+// pass; their cookies are dropped so that only the token authenticates them.
+func checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		r.Header.Del("Cookie")
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if !sameOrigin(r, origin) {
+			respondError(w, "Cross-origin request blocked", http.StatusForbidden)
+			return false
+		}
+	} else if referer := r.Header.Get("Referer"); referer != "" && !sameOrigin(r, referer) {
+		respondError(w, "Cross-origin request blocked", http.StatusForbidden)
+		return false
+	}
+
+	session, _ := store.Get(r, "csrf")
+	expected, _ := session.Values["token"].(string)
+	got := r.Header.Get(csrfHeader)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(got), []byte(expected)) != 1 {
+		respondError(w, "Invalid CSRF token", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// cookielessMiddleware is corsMiddleware for anonymous endpoints that work
+// from the request alone, such as the application form or the token of an
+// email link. They are called from emails and other clients without a CSRF
+// token; a forged request can do nothing there its sender could not do
+// directly. The Cookie header is dropped to keep it that way.
+func cookielessMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowCORS(w, r) {
+			return
+		}
+		r.Header.Del("Cookie")
+		next(w, r)
+	}
+}
+
+// csrfHandler godoc
+// @Summary CSRF token
+// @Description Token to send in the X-CSRF-Token header of every POST, PUT and DELETE made with cookies. It stays the same for the browser session; /me returns it too.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /csrf [get]
+func csrfHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, err := csrfToken(w, r)
+	if err != nil {
+		log.Printf("Error saving session: %v", err)
+		respondError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]string{"csrf_token": token}, http.StatusOK)
+}
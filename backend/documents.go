@@ -0,0 +1,228 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxArchiveApplications caps the applications in one documents archive
+var maxArchiveApplications = parseInt(getEnv("DOCUMENTS_MAX_APPLICATIONS", "500"))
+
+func parseInt(v string) int {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Invalid number %q: %v", v, err)
+	}
+	return n
+}
+
+// sanitizeName turns a candidate name into a portable folder name
+func sanitizeName(name string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			b.WriteRune(r)
+			lastUnderscore = false
+		} else if !lastUnderscore {
+			b.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+
+	s := strings.Trim(b.String(), "_")
+	if runes := []rune(s); len(runes) > 60 {
+		s = strings.Trim(string(runes[:60]), "_")
+	}
+	if s == "" {
+		s = "candidate"
+	}
+	return s
+}
+
+// uploadedFile resolves a stored document path, refusing anything that
+// points outside the uploads directory.
+func uploadedFile(path string) (string, bool) {
+	clean := filepath.Clean(path)
+	if path == "" || !strings.HasPrefix(clean, "uploads"+string(filepath.Separator)) {
+		return "", false
+	}
+	return clean, true
+}
+
+// addZipFile copies a document into the archive. PDFs are already
+// compressed, so they are stored as-is.
+func addZipFile(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// downloadDocuments godoc
+// @Summary Download candidate documents
+// @Description Admin: stream a ZIP with one folder per candidate holding the CV and motivation letter of each of their applications, prefixed with the application ID, plus an index.csv. Accepts the same filters as the list endpoint and the same supervisor scoping; at least one filter or ids is required, and an archive holds at most DOCUMENTS_MAX_APPLICATIONS applications (500 by default).
+// @Tags Admin
+// @Produce application/zip
+// @Security SessionAuth
+// @Param ids query string false "Comma-separated application IDs"
+// @Param status query string false "Status"
+// @Param campaign_id query int false "Campaign ID"
+// @Param subject query string false "Subject name"
+// @Success 200 {file} file
+// @Failure 400 {string} string
+// @Router /applications/documents [get]
+func downloadDocuments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	where, args, err := applicationFilters(r.URL.Query())
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if where == "" {
+		respondError(w, "Select applications with ids or at least one filter", http.StatusBadRequest)
+		return
+	}
+	where, args = scopeOf(r).filter(where, args)
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM applications a`+where, args...).Scan(&count); err != nil {
+		log.Printf("Error counting applications: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if count > maxArchiveApplications {
+		respondError(w, "Too many applications for one archive, at most "+strconv.Itoa(maxArchiveApplications), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT `+applicationColumns+`
+		FROM applications a`+where+` ORDER BY a.full_name, a.candidate_id, a.id
+	`, args...)
+	if err != nil {
+		log.Printf("Error fetching applications: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="documents-`+time.Now().Format("20060102")+`.zip"`)
+
+	zw := zip.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	index := [][]string{{
+		"id", "full_name", "email", "university", "field_of_study",
+		"degree_level", "status", "subjects", "folder", "cv", "motivation",
+	}}
+
+	// Every application that reached the archive is audited, even if the
+	// client goes away midway.
+	var downloaded []int
+	folders := map[int]string{}
+	defer func() {
+		recordAudit(r, http.StatusOK, "documents.download", "applications", "", nil,
+			map[string]interface{}{"ids": downloaded})
+	}()
+
+	// Headers are already sent, so errors from here on can only be logged.
+	for rows.Next() {
+		a, err := scanApplication(rows)
+		if err != nil {
+			log.Printf("Error scanning application: %v", err)
+			continue
+		}
+
+		downloaded = append(downloaded, a.ID)
+		// A candidate's applications share one folder, named after the first
+		// of them, and their files are told apart by the application id.
+		folder, ok := folders[a.CandidateID]
+		if !ok {
+			folder = sanitizeName(a.FullName) + "_" + strconv.Itoa(a.CandidateID)
+			folders[a.CandidateID] = folder
+		}
+		docs := map[string]string{"cv": a.CVFilePath}
+		if a.MotivationFilePath != nil {
+			docs["motivation"] = *a.MotivationFilePath
+		}
+
+		included := map[string]string{}
+		for _, kind := range []string{"cv", "motivation"} {
+			path, ok := uploadedFile(docs[kind])
+			if !ok {
+				continue
+			}
+			name := folder + "/" + strconv.Itoa(a.ID) + "_" + kind + strings.ToLower(filepath.Ext(path))
+			if err := addZipFile(zw, name, path); err != nil {
+				log.Printf("Error adding %s of application %d: %v", kind, a.ID, err)
+				included[kind] = "missing"
+				continue
+			}
+			included[kind] = name
+		}
+
+		index = append(index, []string{
+			strconv.Itoa(a.ID), a.FullName, a.Email, a.University, a.FieldOfStudy,
+			a.DegreeLevel, a.Status, strings.Join(a.Subjects, "; "), folder,
+			included["cv"], included["motivation"],
+		})
+
+		if err := zw.Flush(); err != nil {
+			log.Printf("Error writing archive: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading applications for archive: %v", err)
+	}
+
+	f, err := zw.Create("index.csv")
+	if err == nil {
+		var out *csvRowWriter
+		if out, err = newCSVRowWriter(f); err == nil {
+			for _, record := range index {
+				if err = out.WriteRow(record); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = out.Close()
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Error writing archive index: %v", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		log.Printf("Error finishing archive: %v", err)
+	}
+}
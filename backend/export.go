@@ -0,0 +1,308 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFlushEvery is how many rows are written between flushes to the client
+const exportFlushEvery = 200
+
+// exportHeader names the exported columns after the JSON fields of
+// ApplicationResponse
+var exportHeader = []string{
+	"id", "full_name", "email", "gender", "phone", "university",
+	"field_of_study", "degree_level", "application_type",
+	"internship_duration", "preferred_working_method", "start_date",
+	"created_at", "cv_file_path", "motivation_file_path", "subjects",
+	"status", "candidate_id", "campaign_id",
+	"consent_version", "consented_at", "keep_profile",
+}
+
+func exportRecord(a ApplicationResponse) []string {
+	opt := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	optInt := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+	return []string{
+		strconv.Itoa(a.ID), a.FullName, a.Email, a.Gender, a.Phone, a.University,
+		a.FieldOfStudy, a.DegreeLevel, a.ApplicationType,
+		a.InternshipDuration, a.PreferredWorkingMethod, opt(a.StartDate),
+		a.CreatedAt, a.CVFilePath, opt(a.MotivationFilePath), strings.Join(a.Subjects, "; "),
+		a.Status, strconv.Itoa(a.CandidateID), optInt(a.CampaignID),
+		optInt(a.ConsentVersion), opt(a.ConsentedAt), strconv.FormatBool(a.KeepProfile),
+	}
+}
+
+// rowWriter is a streaming spreadsheet encoder
+type rowWriter interface {
+	WriteRow(cells []string) error
+	Flush() error
+	Close() error
+}
+
+// csvRowWriter writes UTF-8 CSV with a byte order mark so Excel does not
+// fall back to the system code page for accented names.
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func newCSVRowWriter(w io.Writer) (*csvRowWriter, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	return &csvRowWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvRowWriter) WriteRow(cells []string) error {
+	safe := make([]string, len(cells))
+	for i, v := range cells {
+		safe[i] = neutralizeFormula(v)
+	}
+	return c.w.Write(safe)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
+
+// neutralizeFormula stops spreadsheet apps from evaluating user input that
+// starts like a formula.
+func neutralizeFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// xlsxRowWriter streams a single-sheet workbook. Cells use inline strings so
+// no shared string table has to be built in memory.
+type xlsxRowWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Applications" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+func newXLSXRowWriter(w io.Writer) (*xlsxRowWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxRowWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxRowWriter) WriteRow(cells []string) error {
+	x.rows++
+	x.sheet.WriteString(`<row r="` + strconv.Itoa(x.rows) + `">`)
+	for _, v := range cells {
+		x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(x.sheet, []byte(v)); err != nil {
+			return err
+		}
+		x.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxRowWriter) Flush() error {
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Flush()
+}
+
+func (x *xlsxRowWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// exportApplications godoc
+// @Summary Export applications
+// @Description Admin: stream applications as CSV or XLSX, with the same filters as the list endpoint
+// @Tags Admin
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security SessionAuth
+// @Param format query string false "csv (default) or xlsx"
+// @Param status query string false "Status"
+// @Param campaign_id query int false "Campaign ID"
+// @Param subject query string false "Subject name"
+// @Param q query string false "Search in name and email"
+// @Param from query string false "Submitted on or after (YYYY-MM-DD)"
+// @Param to query string false "Submitted on or before (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {string} string
+// @Router /applications/export [get]
+func exportApplications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		respondError(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	where, args, err := applicationFilters(r.URL.Query())
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT `+applicationColumns+`
+		FROM applications a`+where+` ORDER BY a.created_at DESC
+	`, args...)
+	if err != nil {
+		log.Printf("Error fetching applications: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	filename := "applications-" + time.Now().Format("20060102") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	var out rowWriter
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		out, err = newCSVRowWriter(w)
+	} else {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		out, err = newXLSXRowWriter(w)
+	}
+	if err != nil {
+		log.Printf("Error starting export: %v", err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if err := out.WriteRow(exportHeader); err != nil {
+		log.Printf("Error writing export: %v", err)
+		return
+	}
+
+	// The export is audited with its filters however far it got. The
+	// search text is left out, it is often a candidate's name.
+	n := 0
+	defer func() {
+		filters := map[string]interface{}{"format": format, "rows": n}
+		for key, values := range r.URL.Query() {
+			if key != "format" && key != "q" && len(values) > 0 {
+				filters[key] = values[0]
+			}
+		}
+		if r.URL.Query().Get("q") != "" {
+			filters["search"] = true
+		}
+		recordAudit(r, http.StatusOK, "applications.export", "applications", "", nil, filters)
+	}()
+
+	// Headers are already sent, so errors from here on can only be logged.
+	for rows.Next() {
+		a, err := scanApplication(rows)
+		if err != nil {
+			log.Printf("Error scanning application: %v", err)
+			continue
+		}
+		if err := out.WriteRow(exportRecord(a)); err != nil {
+			log.Printf("Error writing export: %v", err)
+			return
+		}
+
+		n++
+		if n%exportFlushEvery == 0 {
+			if err := out.Flush(); err != nil {
+				log.Printf("Error writing export: %v", err)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading applications for export: %v", err)
+	}
+
+	if err := out.Close(); err != nil {
+		log.Printf("Error finishing export: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsEvent is a single VEVENT of an iCalendar document
+type icsEvent struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Cancelled   bool
+
+	// Invitations (METHOD:REQUEST or CANCEL) must name who sends them and
+	// who they are for, RFC 5546 3.2.2 and 3.2.5
+	Organizer icsAddress
+	Attendee  icsAddress
+}
+
+// icsAddress is a calendar user, by email address and display name
+type icsAddress struct {
+	Email string
+	Name  string
+}
+
+// property renders a calendar user property such as ORGANIZER, with the
+// display name as a quoted parameter value, which cannot hold DQUOTE.
+func (a icsAddress) property(name, params string) string {
+	cn := strings.Map(func(r rune) rune {
+		if r == '"' || r < ' ' {
+			return -1
+		}
+		return r
+	}, a.Name)
+	return name + `;CN="` + cn + `"` + params + ":mailto:" + a.Email
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsFold splits content lines longer than 75 octets as RFC 5545 requires,
+// without cutting UTF-8 sequences.
+func icsFold(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
+
+// buildICS renders a calendar. method is REQUEST or CANCEL for email
+// invitations and empty for subscription feeds.
+func buildICS(name, method string, events []icsEvent) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Internship Platform//Interviews//EN",
+		"CALSCALE:GREGORIAN",
+	}
+	if method != "" {
+		lines = append(lines, "METHOD:"+method)
+	}
+	if name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+icsEscaper.Replace(name))
+	}
+
+	stamp := icsTime(time.Now())
+	for _, e := range events {
+		status := "CONFIRMED"
+		if e.Cancelled {
+			status = "CANCELLED"
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+e.UID,
+			"SEQUENCE:"+strconv.Itoa(e.Sequence),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+icsTime(e.Start),
+			"DTEND:"+icsTime(e.End),
+			"SUMMARY:"+icsEscaper.Replace(e.Summary),
+			"STATUS:"+status,
+		)
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+icsEscaper.Replace(e.Description))
+		}
+		if e.Location != "" {
+			lines = append(lines, "LOCATION:"+icsEscaper.Replace(e.Location))
+		}
+		if e.URL != "" {
+			lines = append(lines, "URL:"+e.URL)
+		}
+		if e.Organizer.Email != "" {
+			lines = append(lines, e.Organizer.property("ORGANIZER", ""))
+		}
+		if e.Attendee.Email != "" {
+			lines = append(lines, e.Attendee.property("ATTENDEE", ";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE"))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for i, l := range lines {
+		lines[i] = icsFold(l)
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
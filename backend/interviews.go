@@ -0,0 +1,945 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	InterviewScheduled = "scheduled"
+	InterviewCancelled = "cancelled"
+
+	interviewBookingPurpose = "interview-booking"
+	interviewBookingTTL     = 14 * 24 * time.Hour
+)
+
+// interviewTimezone is used for the times written in candidate emails; the
+// attached invitation carries UTC times that calendar clients convert.
+var interviewTimezone = loadLocation(getEnv("APP_TIMEZONE", "UTC"))
+
+var (
+	errSlotNotFound      = errors.New("Slot not found")
+	errInterviewNotFound = errors.New("Interview not found")
+	errSlotTaken         = errors.New("Slot is already booked")
+	errSlotStarted       = errors.New("Slot has already started")
+	errCandidateBusy     = errors.New("Candidate already has an interview at that time")
+	errApplicationClosed = errors.New("Application is closed")
+	errNotScheduled      = errors.New("Interview is not scheduled")
+)
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatalf("Invalid timezone %q: %v", name, err)
+	}
+	return loc
+}
+
+// InterviewSlot is a period an interviewer is available for one interview
+type InterviewSlot struct {
+	ID            int    `json:"id"`
+	InterviewerID int    `json:"interviewer_id"`
+	Interviewer   string `json:"interviewer"`
+	StartsAt      string `json:"starts_at"`
+	EndsAt        string `json:"ends_at"`
+	Location      string `json:"location,omitempty"`
+	MeetingURL    string `json:"meeting_url,omitempty"`
+	InterviewID   *int   `json:"interview_id,omitempty"`
+	ApplicationID *int   `json:"application_id,omitempty"`
+}
+
+// AvailableSlot is a free slot as offered to a candidate
+type AvailableSlot struct {
+	ID       int    `json:"id"`
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+}
+
+// Interview is the booking of a slot for an application
+type Interview struct {
+	ID            int    `json:"id"`
+	SlotID        int    `json:"slot_id"`
+	ApplicationID int    `json:"application_id"`
+	FullName      string `json:"full_name"`
+	Email         string `json:"email"`
+	InterviewerID int    `json:"interviewer_id"`
+	Interviewer   string `json:"interviewer"`
+	StartsAt      string `json:"starts_at"`
+	EndsAt        string `json:"ends_at"`
+	Location      string `json:"location,omitempty"`
+	MeetingURL    string `json:"meeting_url,omitempty"`
+	Status        string `json:"status"`
+	BookedBy      string `json:"booked_by"`
+	CreatedAt     string `json:"created_at"`
+
+	start, end time.Time
+	sequence   int
+}
+
+const interviewColumns = `i.id, i.slot_id, i.application_id, a.full_name, a.email,
+	s.interviewer_id, u.username, s.starts_at, s.ends_at, s.location, s.meeting_url,
+	i.status, i.booked_by, i.sequence, i.created_at`
+
+const interviewJoins = ` FROM interviews i
+	JOIN interview_slots s ON s.id = i.slot_id
+	JOIN applications a ON a.id = i.application_id
+	JOIN users u ON u.id = s.interviewer_id`
+
+func scanInterview(row rowScanner) (Interview, error) {
+	var iv Interview
+	var created time.Time
+	err := row.Scan(&iv.ID, &iv.SlotID, &iv.ApplicationID, &iv.FullName, &iv.Email,
+		&iv.InterviewerID, &iv.Interviewer, &iv.start, &iv.end, &iv.Location, &iv.MeetingURL,
+		&iv.Status, &iv.BookedBy, &iv.sequence, &created)
+	if err != nil {
+		return iv, err
+	}
+	iv.StartsAt = iv.start.Format(time.RFC3339)
+	iv.EndsAt = iv.end.Format(time.RFC3339)
+	iv.CreatedAt = created.Format(time.RFC3339)
+	return iv, nil
+}
+
+func (iv Interview) icsEvent() icsEvent {
+	host := "localhost"
+	if u, err := url.Parse(appURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	description := "Interviewer: " + iv.Interviewer + "\nApplication #" + strconv.Itoa(iv.ApplicationID)
+	if iv.MeetingURL != "" {
+		description += "\nVideo call: " + iv.MeetingURL
+	}
+	return icsEvent{
+		UID:         fmt.Sprintf("interview-%d@%s", iv.ID, host),
+		Sequence:    iv.sequence,
+		Start:       iv.start,
+		End:         iv.end,
+		Summary:     "Internship interview: " + iv.FullName,
+		Description: description,
+		Location:    iv.Location,
+		URL:         iv.MeetingURL,
+		Cancelled:   iv.Status == InterviewCancelled,
+		Organizer:   icsAddress{Email: mailFromAddress(), Name: "Recruitment (" + iv.Interviewer + ")"},
+		Attendee:    icsAddress{Email: iv.Email, Name: iv.FullName},
+	}
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func loadInterview(q queryer, id int) (Interview, error) {
+	return scanInterview(q.QueryRow(`SELECT `+interviewColumns+interviewJoins+` WHERE i.id=$1`, id))
+}
+
+// notifyInterview emails the candidate the current state of an interview
+// with a calendar invitation or cancellation attached. Anonymized
+// applications have no address left and are skipped.
+func notifyInterview(ex execer, iv Interview, rescheduled bool, reason string) error {
+	if iv.Email == "" {
+		return nil
+	}
+	data := map[string]interface{}{
+		"ApplicationID": iv.ApplicationID,
+		"FullName":      iv.FullName,
+		"StartsAt":      iv.start.In(interviewTimezone).Format("Monday 2 January 2006, 15:04 MST"),
+		"Location":      iv.Location,
+		"MeetingURL":    iv.MeetingURL,
+		"Rescheduled":   rescheduled,
+		"Reason":        reason,
+		"ICSMethod":     "REQUEST",
+	}
+	templateName := "interview_invitation"
+	if iv.Status == InterviewCancelled {
+		templateName = "interview_cancelled"
+		data["ICSMethod"] = "CANCEL"
+	}
+	data["ICS"] = buildICS("", data["ICSMethod"].(string), []icsEvent{iv.icsEvent()})
+	return enqueueEmail(ex, iv.Email, templateName, data)
+}
+
+// bookInterview books slotID for an application, or moves interviewID to it
+// when non-zero. The application row is locked first and the slot second on
+// every path, so concurrent bookings cannot both pass the conflict checks.
+func bookInterview(tx *sql.Tx, appID, slotID, interviewID int, bookedBy string) (Interview, error) {
+	var status string
+	var anonymized bool
+	if err := tx.QueryRow(`
+		SELECT status, anonymized_at IS NOT NULL FROM applications WHERE id=$1 FOR UPDATE
+	`, appID).Scan(&status, &anonymized); err != nil {
+		return Interview{}, err
+	}
+	if anonymized {
+		return Interview{}, errApplicationAnonymized
+	}
+	if isClosedStatus(status) {
+		return Interview{}, errApplicationClosed
+	}
+
+	var start, end time.Time
+	err := tx.QueryRow(`SELECT starts_at, ends_at FROM interview_slots WHERE id=$1 FOR UPDATE`, slotID).Scan(&start, &end)
+	if err == sql.ErrNoRows {
+		return Interview{}, errSlotNotFound
+	} else if err != nil {
+		return Interview{}, err
+	}
+	if !start.After(time.Now()) {
+		return Interview{}, errSlotStarted
+	}
+
+	var taken, busy bool
+	if err := tx.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM interviews WHERE slot_id=$1 AND status='scheduled' AND id<>$2),
+			EXISTS (
+				SELECT 1 FROM interviews i
+				JOIN interview_slots s ON s.id = i.slot_id
+				WHERE i.application_id=$3 AND i.status='scheduled' AND i.id<>$2
+				AND s.starts_at < $5 AND s.ends_at > $4
+			)
+	`, slotID, interviewID, appID, start, end).Scan(&taken, &busy); err != nil {
+		return Interview{}, err
+	}
+	if taken {
+		return Interview{}, errSlotTaken
+	}
+	if busy {
+		return Interview{}, errCandidateBusy
+	}
+
+	if interviewID == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO interviews (slot_id, application_id, booked_by)
+			VALUES ($1, $2, $3) RETURNING id
+		`, slotID, appID, bookedBy).Scan(&interviewID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE interviews SET slot_id=$1, sequence=sequence+1, updated_at=NOW()
+			WHERE id=$2
+		`, slotID, interviewID)
+	}
+	if err != nil {
+		return Interview{}, err
+	}
+	return loadInterview(tx, interviewID)
+}
+
+// lockInterview locks a scheduled interview and its application, in the same
+// order as bookInterview.
+func lockInterview(tx *sql.Tx, interviewID int) (appID int, err error) {
+	err = tx.QueryRow(`SELECT application_id FROM interviews WHERE id=$1`, interviewID).Scan(&appID)
+	if err == sql.ErrNoRows {
+		return 0, errInterviewNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`SELECT 1 FROM applications WHERE id=$1 FOR UPDATE`, appID); err != nil {
+		return 0, err
+	}
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM interviews WHERE id=$1 FOR UPDATE`, interviewID).Scan(&status); err != nil {
+		return 0, err
+	}
+	if status != InterviewScheduled {
+		return 0, errNotScheduled
+	}
+	return appID, nil
+}
+
+// scheduleInterview books or reschedules an interview and notifies the candidate
+func scheduleInterview(appID, slotID, interviewID int, bookedBy string) (Interview, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Interview{}, err
+	}
+	defer tx.Rollback()
+
+	if interviewID != 0 {
+		owner, err := lockInterview(tx, interviewID)
+		if err != nil {
+			return Interview{}, err
+		}
+		if appID != 0 && owner != appID {
+			return Interview{}, errInterviewNotFound
+		}
+		appID = owner
+	}
+
+	iv, err := bookInterview(tx, appID, slotID, interviewID, bookedBy)
+	if err != nil {
+		return Interview{}, err
+	}
+	if err := notifyInterview(tx, iv, interviewID != 0, ""); err != nil {
+		return Interview{}, err
+	}
+	return iv, tx.Commit()
+}
+
+// cancelInterview frees the slot of an interview and notifies the candidate
+func cancelInterview(interviewID, appID int, reason string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	owner, err := lockInterview(tx, interviewID)
+	if err != nil {
+		return err
+	}
+	if appID != 0 && owner != appID {
+		return errInterviewNotFound
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE interviews SET status=$1, sequence=sequence+1, updated_at=NOW() WHERE id=$2
+	`, InterviewCancelled, interviewID); err != nil {
+		return err
+	}
+	iv, err := loadInterview(tx, interviewID)
+	if err != nil {
+		return err
+	}
+	if err := notifyInterview(tx, iv, false, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// respondInterviewError maps booking errors to HTTP responses
+func respondInterviewError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		respondError(w, "Application not found", http.StatusNotFound)
+	case errSlotNotFound, errInterviewNotFound:
+		respondError(w, err.Error(), http.StatusNotFound)
+	case errSlotTaken, errSlotStarted, errCandidateBusy, errApplicationClosed, errNotScheduled, errApplicationAnonymized:
+		respondError(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error scheduling interview: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+	}
+}
+
+// bookingLink is the self-service link sent with interview invitations
+func bookingLink(appID int) string {
+	return appURL + "/interview?token=" + url.QueryEscape(signToken(interviewBookingPurpose, appID, interviewBookingTTL))
+}
+
+// interviewSlots godoc
+// @Summary Manage interview slots
+// @Description Admin: list slots (GET), create a slot for an interviewer (POST, defaults to the current user) or delete a slot that is not booked (DELETE). Slots of the same interviewer may not overlap.
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param interviewer_id query int false "Interviewer user ID"
+// @Param from query string false "Starting on or after (YYYY-MM-DD), default today"
+// @Param to query string false "Starting on or before (YYYY-MM-DD)"
+// @Param available query bool false "Only slots that are not booked"
+// @Param body body object{interviewer_id=int,starts_at=string,ends_at=string,location=string,meeting_url=string} false "Slot payload (POST), {id} (DELETE)"
+// @Success 200 {array} InterviewSlot
+// @Success 201 {object} InterviewSlot
+// @Failure 400 {string} string
+// @Failure 409 {string} string
+// @Router /interviews/slots [get]
+// @Router /interviews/slots [post]
+// @Router /interviews/slots [delete]
+func interviewSlots(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		var conds []string
+		var args []interface{}
+		add := func(cond string, arg interface{}) {
+			args = append(args, arg)
+			conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+		}
+
+		if v := q.Get("interviewer_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				respondError(w, "invalid interviewer_id", http.StatusBadRequest)
+				return
+			}
+			add("s.interviewer_id = ?", id)
+		}
+		from := time.Now().Format("2006-01-02")
+		if v := q.Get("from"); v != "" {
+			from = v
+		}
+		if _, err := time.Parse("2006-01-02", from); err != nil {
+			respondError(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+		add("s.starts_at >= ?::DATE", from)
+		if v := q.Get("to"); v != "" {
+			to, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				respondError(w, "invalid to", http.StatusBadRequest)
+				return
+			}
+			add("s.starts_at < ?::DATE", to.AddDate(0, 0, 1).Format("2006-01-02"))
+		}
+		if q.Get("available") == "true" {
+			conds = append(conds, "i.id IS NULL")
+		}
+
+		rows, err := db.Query(`
+			SELECT s.id, s.interviewer_id, u.username, s.starts_at, s.ends_at,
+			s.location, s.meeting_url, i.id, i.application_id
+			FROM interview_slots s
+			JOIN users u ON u.id = s.interviewer_id
+			LEFT JOIN interviews i ON i.slot_id = s.id AND i.status = 'scheduled'
+			WHERE `+strings.Join(conds, " AND ")+`
+			ORDER BY s.starts_at, s.id
+		`, args...)
+		if err != nil {
+			log.Printf("Error fetching interview slots: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		slots := []InterviewSlot{}
+		for rows.Next() {
+			var s InterviewSlot
+			var start, end time.Time
+			var interviewID, appID sql.NullInt64
+			if err := rows.Scan(&s.ID, &s.InterviewerID, &s.Interviewer, &start, &end,
+				&s.Location, &s.MeetingURL, &interviewID, &appID); err != nil {
+				log.Printf("Error scanning interview slot: %v", err)
+				continue
+			}
+			s.StartsAt = start.Format(time.RFC3339)
+			s.EndsAt = end.Format(time.RFC3339)
+			if interviewID.Valid {
+				iid, aid := int(interviewID.Int64), int(appID.Int64)
+				s.InterviewID, s.ApplicationID = &iid, &aid
+			}
+			slots = append(slots, s)
+		}
+		respondJSON(w, slots, http.StatusOK)
+
+	case http.MethodPost:
+		var body struct {
+			InterviewerID int    `json:"interviewer_id"`
+			StartsAt      string `json:"starts_at"`
+			EndsAt        string `json:"ends_at"`
+			Location      string `json:"location"`
+			MeetingURL    string `json:"meeting_url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if body.InterviewerID == 0 {
+			body.InterviewerID, _ = currentUserID(r)
+		}
+		start, err1 := time.Parse(time.RFC3339, body.StartsAt)
+		end, err2 := time.Parse(time.RFC3339, body.EndsAt)
+		if err1 != nil || err2 != nil {
+			respondError(w, "starts_at and ends_at must be RFC 3339 timestamps", http.StatusBadRequest)
+			return
+		}
+		if !end.After(start) || !start.After(time.Now()) {
+			respondError(w, "Slot must end after it starts and start in the future", http.StatusBadRequest)
+			return
+		}
+		body.Location = strings.TrimSpace(body.Location)
+		body.MeetingURL = strings.TrimSpace(body.MeetingURL)
+		if body.MeetingURL != "" {
+			if u, err := url.Parse(body.MeetingURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				respondError(w, "Invalid meeting URL", http.StatusBadRequest)
+				return
+			}
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		// Locking the interviewer serialises slot creation per interviewer
+		s := InterviewSlot{
+			InterviewerID: body.InterviewerID,
+			StartsAt:      start.Format(time.RFC3339),
+			EndsAt:        end.Format(time.RFC3339),
+			Location:      body.Location,
+			MeetingURL:    body.MeetingURL,
+		}
+		err = tx.QueryRow(`
+			SELECT username FROM users WHERE id=$1 AND role = ANY($2) FOR UPDATE
+		`, body.InterviewerID, rolesWith(PermInterviewsManage)).Scan(&s.Interviewer)
+		if err == sql.ErrNoRows {
+			respondError(w, "Unknown interviewer", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Printf("Error fetching interviewer: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		var overlap bool
+		if err := tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM interview_slots
+				WHERE interviewer_id=$1 AND starts_at < $3 AND ends_at > $2
+			)
+		`, body.InterviewerID, start, end).Scan(&overlap); err != nil {
+			log.Printf("Error checking slot overlap: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if overlap {
+			respondError(w, "Slot overlaps another slot of this interviewer", http.StatusConflict)
+			return
+		}
+
+		if err := tx.QueryRow(`
+			INSERT INTO interview_slots (interviewer_id, starts_at, ends_at, location, meeting_url)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id
+		`, body.InterviewerID, start, end, body.Location, body.MeetingURL).Scan(&s.ID); err != nil {
+			log.Printf("Error creating interview slot: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing interview slot: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, s, http.StatusCreated)
+
+	case http.MethodDelete:
+		var body struct {
+			ID int `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		result, err := db.Exec(`
+			DELETE FROM interview_slots s
+			WHERE s.id=$1 AND NOT EXISTS (
+				SELECT 1 FROM interviews i WHERE i.slot_id = s.id AND i.status = 'scheduled'
+			)
+		`, body.ID)
+		if err != nil {
+			log.Printf("Error deleting interview slot: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			respondError(w, "Slot not found or booked; cancel the interview first", http.StatusConflict)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// interviewsHandler godoc
+// @Summary Manage interviews
+// @Description Admin: list interviews (GET), book a slot for an application (POST) or move an interview to another slot (PUT). The candidate is emailed a calendar invitation.
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param application_id query int false "Application ID"
+// @Param interviewer_id query int false "Interviewer user ID"
+// @Param status query string false "scheduled or cancelled"
+// @Param body body object{id=int,application_id=int,slot_id=int} false "Booking (POST) or {id, slot_id} (PUT)"
+// @Success 200 {array} Interview
+// @Success 201 {object} Interview
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /interviews [get]
+// @Router /interviews [post]
+// @Router /interviews [put]
+func interviewsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		var conds []string
+		var args []interface{}
+		for _, f := range []struct{ param, column string }{
+			{"application_id", "i.application_id"},
+			{"interviewer_id", "s.interviewer_id"},
+		} {
+			if v := q.Get(f.param); v != "" {
+				id, err := strconv.Atoi(v)
+				if err != nil {
+					respondError(w, "invalid "+f.param, http.StatusBadRequest)
+					return
+				}
+				args = append(args, id)
+				conds = append(conds, f.column+" = $"+strconv.Itoa(len(args)))
+			}
+		}
+		if v := q.Get("status"); v != "" {
+			args = append(args, v)
+			conds = append(conds, "i.status = $"+strconv.Itoa(len(args)))
+		}
+		where := ""
+		if len(conds) > 0 {
+			where = " WHERE " + strings.Join(conds, " AND ")
+		}
+
+		rows, err := db.Query(`SELECT `+interviewColumns+interviewJoins+where+` ORDER BY s.starts_at, i.id`, args...)
+		if err != nil {
+			log.Printf("Error fetching interviews: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		interviews := []Interview{}
+		for rows.Next() {
+			iv, err := scanInterview(rows)
+			if err != nil {
+				log.Printf("Error scanning interview: %v", err)
+				continue
+			}
+			interviews = append(interviews, iv)
+		}
+		respondJSON(w, interviews, http.StatusOK)
+
+	case http.MethodPost, http.MethodPut:
+		var body struct {
+			ID            int `json:"id"`
+			ApplicationID int `json:"application_id"`
+			SlotID        int `json:"slot_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		code := http.StatusCreated
+		if r.Method == http.MethodPost {
+			if body.ApplicationID == 0 || body.SlotID == 0 {
+				respondError(w, "application_id and slot_id are required", http.StatusBadRequest)
+				return
+			}
+			body.ID = 0
+		} else {
+			if body.ID == 0 || body.SlotID == 0 {
+				respondError(w, "id and slot_id are required", http.StatusBadRequest)
+				return
+			}
+			body.ApplicationID = 0
+			code = http.StatusOK
+		}
+
+		iv, err := scheduleInterview(body.ApplicationID, body.SlotID, body.ID, "hr")
+		if err != nil {
+			respondInterviewError(w, err)
+			return
+		}
+		respondJSON(w, iv, code)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// cancelInterviewHandler godoc
+// @Summary Cancel interview
+// @Description Admin: cancel a scheduled interview, freeing its slot. The candidate is emailed a calendar cancellation.
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int,reason=string} true "Cancellation payload"
+// @Success 200 {object} map[string]bool
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /interviews/cancel [post]
+func cancelInterviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ID     int    `json:"id"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := cancelInterview(body.ID, 0, strings.TrimSpace(body.Reason)); err != nil {
+		respondInterviewError(w, err)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// interviewBooking godoc
+// @Summary Self-service interview booking
+// @Description Candidate, authenticated by the link from the interview invitation: list free slots and current bookings (GET), book a slot or move a booking to another slot (POST), or cancel a booking (DELETE).
+// @Tags Interviews
+// @Accept json
+// @Produce json
+// @Param token query string false "Booking token (GET)"
+// @Param body body object{token=string,slot_id=int,interview_id=int} false "Booking payload (POST), {token, interview_id} (DELETE)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {string} string
+// @Failure 409 {string} string
+// @Router /interviews/booking [get]
+// @Router /interviews/booking [post]
+// @Router /interviews/booking [delete]
+func interviewBooking(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token       string `json:"token"`
+		SlotID      int    `json:"slot_id"`
+		InterviewID int    `json:"interview_id"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		body.Token = r.URL.Query().Get("token")
+	case http.MethodPost, http.MethodDelete:
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	appID, err := verifyToken(interviewBookingPurpose, body.Token)
+	if err != nil {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`SELECT `+interviewColumns+interviewJoins+`
+			WHERE i.application_id=$1 AND i.status='scheduled' AND s.ends_at > NOW()
+			ORDER BY s.starts_at`, appID)
+		if err != nil {
+			log.Printf("Error fetching interviews: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		booked := []Interview{}
+		for rows.Next() {
+			iv, err := scanInterview(rows)
+			if err != nil {
+				log.Printf("Error scanning interview: %v", err)
+				continue
+			}
+			booked = append(booked, iv)
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT s.id, s.starts_at, s.ends_at
+			FROM interview_slots s
+			WHERE s.starts_at > NOW() AND NOT EXISTS (
+				SELECT 1 FROM interviews i WHERE i.slot_id = s.id AND i.status = 'scheduled'
+			)
+			ORDER BY s.starts_at, s.id
+		`)
+		if err != nil {
+			log.Printf("Error fetching interview slots: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		slots := []AvailableSlot{}
+		for rows.Next() {
+			var s AvailableSlot
+			var start, end time.Time
+			if err := rows.Scan(&s.ID, &start, &end); err != nil {
+				log.Printf("Error scanning interview slot: %v", err)
+				continue
+			}
+			s.StartsAt = start.Format(time.RFC3339)
+			s.EndsAt = end.Format(time.RFC3339)
+			slots = append(slots, s)
+		}
+		respondJSON(w, map[string]interface{}{
+			"application_id": appID,
+			"interviews":     booked,
+			"slots":          slots,
+		}, http.StatusOK)
+
+	case http.MethodPost:
+		if body.SlotID == 0 {
+			respondError(w, "slot_id is required", http.StatusBadRequest)
+			return
+		}
+		if body.InterviewID == 0 {
+			var upcoming bool
+			if err := db.QueryRow(`
+				SELECT EXISTS (
+					SELECT 1 FROM interviews i JOIN interview_slots s ON s.id = i.slot_id
+					WHERE i.application_id=$1 AND i.status='scheduled' AND s.starts_at > NOW()
+				)
+			`, appID).Scan(&upcoming); err != nil {
+				log.Printf("Error checking interviews: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if upcoming {
+				respondError(w, "You already have an interview booked; reschedule it instead", http.StatusConflict)
+				return
+			}
+		}
+
+		iv, err := scheduleInterview(appID, body.SlotID, body.InterviewID, "candidate")
+		if err != nil {
+			respondInterviewError(w, err)
+			return
+		}
+		respondJSON(w, iv, http.StatusOK)
+
+	case http.MethodDelete:
+		if err := cancelInterview(body.InterviewID, appID, "Cancelled at your request"); err != nil {
+			respondInterviewError(w, err)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+	}
+}
+
+// calendarFeedURL godoc
+// @Summary Interview calendar subscription link
+// @Description Admin: whether the current user has a calendar subscription link (GET), a new private URL of their interviews in iCalendar format to subscribe to from a calendar client (POST), which replaces any earlier one, or revoke the link (DELETE). The link stops working as soon as its owner no longer holds interviews:manage.
+// @Tags Interviews
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /interviews/feed [get]
+// @Router /interviews/feed [post]
+// @Router /interviews/feed [delete]
+func calendarFeedURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var active bool
+		if err := db.QueryRow(`
+			SELECT calendar_feed_hash IS NOT NULL FROM users WHERE id=$1
+		`, userID).Scan(&active); err != nil {
+			log.Printf("Error fetching calendar feed: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string]bool{"active": active}, http.StatusOK)
+
+	case http.MethodPost:
+		// Only the hash is stored, so the link is shown once
+		secret, err := randomString()
+		if err != nil {
+			log.Printf("Error generating calendar feed token: %v", err)
+			respondError(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := db.Exec(`UPDATE users SET calendar_feed_hash=$1 WHERE id=$2`, hashToken(secret), userID); err != nil {
+			log.Printf("Error saving calendar feed: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		feed := scheme + "://" + r.Host + "/interviews/calendar.ics?token=" + url.QueryEscape(secret)
+		respondJSON(w, map[string]string{"url": feed}, http.StatusOK)
+
+	case http.MethodDelete:
+		if _, err := db.Exec(`UPDATE users SET calendar_feed_hash=NULL WHERE id=$1`, userID); err != nil {
+			log.Printf("Error revoking calendar feed: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// calendarFeed godoc
+// @Summary Interview calendar feed
+// @Description The interviews of one interviewer from the last 30 days on, in iCalendar format, authenticated by the token of the subscription link. The interviewer must still hold interviews:manage.
+// @Tags Interviews
+// @Produce text/calendar
+// @Param token query string true "Feed token"
+// @Success 200 {file} file
+// @Failure 401 {string} string
+// @Router /interviews/calendar.ics [get]
+func calendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var userID int
+	err := db.QueryRow(`
+		SELECT id FROM users WHERE calendar_feed_hash=$1 AND role = ANY($2)
+	`, hashToken(r.URL.Query().Get("token")), rolesWith(PermInterviewsManage)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Invalid or revoked link", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error checking calendar feed: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`SELECT `+interviewColumns+interviewJoins+`
+		WHERE s.interviewer_id=$1 AND i.status='scheduled' AND s.starts_at > NOW() - INTERVAL '30 days'
+		ORDER BY s.starts_at`, userID)
+	if err != nil {
+		log.Printf("Error fetching interviews: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var events []icsEvent
+	for rows.Next() {
+		iv, err := scanInterview(rows)
+		if err != nil {
+			log.Printf("Error scanning interview: %v", err)
+			continue
+		}
+		events = append(events, iv.icsEvent())
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="interviews.ics"`)
+	w.Write([]byte(buildICS("Internship interviews", "", events)))
+}
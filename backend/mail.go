@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var (
+	smtpHost = getEnv("SMTP_HOST", "")
+	smtpPort = getEnv("SMTP_PORT", "1025")
+	smtpUser = getEnv("SMTP_USERNAME", "")
+	smtpPass = getEnv("SMTP_PASSWORD", "")
+	mailFrom = getEnv("MAIL_FROM", "no-reply@localhost")
+	appURL   = getEnv("APP_URL", "http://localhost:3000")
+)
+
+// mailFromAddress is the bare address of MAIL_FROM, which may carry a
+// display name
+func mailFromAddress() string {
+	if addr, err := mail.ParseAddress(mailFrom); err == nil {
+		return addr.Address
+	}
+	return mailFrom
+}
+
+// mailAttachment is a file sent along with an email
+type mailAttachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// sendMail delivers a plain-text email, as a multipart message when there
+// are attachments. Without SMTP_HOST only the recipient and subject are
+// logged: bodies carry sign-in and confirmation links, which must not end up
+// in logs. Run MailHog to read them in development.
+func sendMail(to, subject, body string, attachments ...mailAttachment) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header for %q", to)
+	}
+	for _, a := range attachments {
+		if strings.ContainsAny(a.Name+a.ContentType, "\r\n\"") {
+			return fmt.Errorf("invalid attachment %q", a.Name)
+		}
+	}
+
+	if smtpHost == "" {
+		log.Printf("SMTP_HOST not set, email to %s: %s (%d attachments)", to, subject, len(attachments))
+		return nil
+	}
+
+	var auth smtp.Auth
+	if smtpUser != "" {
+		auth = smtp.PlainAuth("", smtpUser, smtpPass, smtpHost)
+	}
+
+	headers := []string{
+		"From: " + mailFrom,
+		"To: " + to,
+		// Subjects carry candidate and author names
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+	}
+
+	var msg bytes.Buffer
+	if len(attachments) == 0 {
+		headers = append(headers, "Content-Type: text/plain; charset=UTF-8", "", body)
+		msg.WriteString(strings.Join(headers, "\r\n"))
+	} else {
+		mw := multipart.NewWriter(&msg)
+		headers = append(headers, `Content-Type: multipart/mixed; boundary="`+mw.Boundary()+`"`, "", "")
+		msg.WriteString(strings.Join(headers, "\r\n"))
+
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+		if err != nil {
+			return err
+		}
+		part.Write([]byte(body))
+
+		for _, a := range attachments {
+			part, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {a.ContentType + `; name="` + a.Name + `"`},
+				"Content-Disposition":       {`attachment; filename="` + a.Name + `"`},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			if err != nil {
+				return err
+			}
+			encoded := base64.StdEncoding.EncodeToString(a.Data)
+			for len(encoded) > 76 {
+				part.Write([]byte(encoded[:76] + "\r\n"))
+				encoded = encoded[76:]
+			}
+			part.Write([]byte(encoded + "\r\n"))
+		}
+		if err := mw.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := smtp.SendMail(smtpHost+":"+smtpPort, auth, mailFromAddress(), []string{to}, msg.Bytes()); err != nil {
+		return fmt.Errorf("sending mail to %s: %w", to, err)
+	}
+	return nil
+}
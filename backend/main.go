@@ -16,6 +16,11 @@
 // @in cookie
 // @name auth
 
+// @securityDefinitions.apikey CSRFToken
+// @in header
+// @name X-CSRF-Token
+// @description Token from /csrf, required on POST, PUT and DELETE requests that carry cookies, /login, /signup and /applicant/login included. Anonymous endpoints working from the application form or an email link token do not need it.
+
 package main
 
 import (
@@ -26,6 +31,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "backend/docs"
@@ -35,11 +42,10 @@ import (
 	"github.com/gorilla/sessions"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var db *sql.DB
-var store = sessions.NewCookieStore([]byte("super-secret-key"))
+var store *sessions.CookieStore
 
 // ApplicationResponse represents an internship application
 type ApplicationResponse struct {
@@ -59,23 +65,44 @@ type ApplicationResponse struct {
 	CVFilePath             string   `json:"cv_file_path"`
 	MotivationFilePath     *string  `json:"motivation_file_path,omitempty"`
 	Subjects               []string `json:"subjects"`
+	Status                 string   `json:"status"`
+	CandidateID            int      `json:"candidate_id"`
+	CampaignID             *int     `json:"campaign_id,omitempty"`
+	ConsentVersion         *int     `json:"consent_version,omitempty"`
+	ConsentedAt            *string  `json:"consented_at,omitempty"`
+	KeepProfile            bool     `json:"keep_profile"`
+	Score                  *float64 `json:"score,omitempty"`
+	ScoreVariance          *float64 `json:"score_variance,omitempty"`
+	Scorecards             int      `json:"scorecards,omitempty"`
 }
 
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Allow-Methods", "PUT, GET, POST, DELETE, OPTIONS")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+		if !allowCORS(w, r) {
+			return
+		}
+		if !checkCSRF(w, r) {
 			return
 		}
 		next(w, r)
 	}
 }
 
+// allowCORS lets the frontend call the API with credentials, and answers
+// preflight requests, in which case it returns false.
+func allowCORS(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Access-Control-Allow-Origin", frontendOrigin)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+csrfHeader)
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, GET, POST, DELETE, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return false
+	}
+	return true
+}
+
 func respondError(w http.ResponseWriter, message string, code int) {
 	http.Error(w, message, code)
 }
@@ -88,26 +115,25 @@ func respondJSON(w http.ResponseWriter, data interface{}, code int) {
 	}
 }
 
-func authRequired(role string, next http.HandlerFunc) http.HandlerFunc {
-	return corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		session, err := store.Get(r, "auth")
-		if err != nil {
-			respondError(w, "Session error", http.StatusInternalServerError)
-			return
-		}
-
-		userRole, ok := session.Values["role"].(string)
-		if !ok {
-			respondError(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
 
-		if role != "" && userRole != role {
-			respondError(w, "Forbidden", http.StatusForbidden)
-			return
-		}
-		next(w, r)
-	})
+// currentUserID returns the back-office user of the request's session, or
+// the owner of its API token
+func currentUserID(r *http.Request) (int, bool) {
+	if t := apiTokenOf(r); t != nil {
+		return t.userID, true
+	}
+	session, err := store.Get(r, "auth")
+	if err != nil {
+		return 0, false
+	}
+	id, ok := session.Values["user_id"].(int)
+	return id, ok
 }
 
 func main() {
@@ -116,6 +142,13 @@ func main() {
 	if dbURL == "" {
 		log.Fatal("DATABASE_URL environment variable is not set")
 	}
+	if len(tokenSecret) == 0 {
+		log.Fatal("TOKEN_SECRET environment variable is not set")
+	}
+	if len(sessionSecret) == 0 {
+		log.Fatal("SESSION_SECRET environment variable is not set")
+	}
+	store = newCookieStore()
 
 	for i := 0; i < 5; i++ {
 		db, err = sql.Open("postgres", dbURL)
@@ -137,24 +170,99 @@ func main() {
 	db.SetConnMaxLifetime(5 * time.Minute)
 	defer db.Close()
 
+	if err := migrate(); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	go runOutboxWorker()
+	go runHRDigest()
+	go runWebhookWorker()
+	go runRetention()
+
 	store.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   86400,
+		MaxAge:   int(sessionMaxAge.Seconds()),
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
 	}
 
+	http.HandleFunc("/csrf", corsMiddleware(csrfHandler))
 	http.HandleFunc("/signup", corsMiddleware(signup))
 	http.HandleFunc("/login", corsMiddleware(login))
 	http.HandleFunc("/logout", corsMiddleware(logout))
+	http.HandleFunc("/verify-email", cookielessMiddleware(verifyEmail))
+	http.HandleFunc("/verify-email/resend", cookielessMiddleware(resendVerification))
+	http.HandleFunc("/password/forgot", cookielessMiddleware(forgotPassword))
+	http.HandleFunc("/password/reset", cookielessMiddleware(resetPassword))
+	http.HandleFunc("/password/change", userRequired(changePassword))
+	http.HandleFunc("/login/2fa", corsMiddleware(loginTwoFactor))
+	http.HandleFunc("/oidc/login", corsMiddleware(oidcLogin))
+	http.HandleFunc("/oidc/callback", corsMiddleware(oidcCallback))
+	http.HandleFunc("/2fa", userRequired(twoFactorHandler))
+	http.HandleFunc("/2fa/enroll", userRequired(enrollTwoFactor))
+	http.HandleFunc("/2fa/enable", userRequired(enableTwoFactor))
+	http.HandleFunc("/2fa/disable", userRequired(disableTwoFactor))
+	http.HandleFunc("/2fa/recovery-codes", userRequired(regenerateRecoveryCodes))
+	http.HandleFunc("/2fa/policy", permissionRequired(PermUsersManage, twoFactorPolicy))
+	http.HandleFunc("/users/2fa/reset", permissionRequired(PermUsersManage, resetTwoFactor))
+	http.HandleFunc("/tokens", permissionRequired(PermTokensCreate, apiTokensHandler))
+	http.HandleFunc("/sessions", userRequired(sessionsHandler))
 	http.HandleFunc("/me", corsMiddleware(me))
 	http.HandleFunc("/email-exists", corsMiddleware(emailExists))
-	http.HandleFunc("/apply", corsMiddleware(applyHandler))
-	http.HandleFunc("/subjects", corsMiddleware(subjectsHandler))
-	http.HandleFunc("/applications", authRequired("admin", listApplications))
-	http.HandleFunc("/subjects/delete", authRequired("admin", deleteSubjects))
-	http.HandleFunc("/weekly-applications", authRequired("admin", weeklyApplications))
-	http.HandleFunc("/uploads/", corsMiddleware(serveFile))
+	http.HandleFunc("/apply", cookielessMiddleware(applyHandler))
+	http.HandleFunc("/privacy-notice", corsMiddleware(privacyNotice))
+	http.HandleFunc("/subjects", writePermissionRequired(PermSubjectsManage, subjectsHandler))
+	http.HandleFunc("/campaigns", corsMiddleware(openCampaigns))
+	http.HandleFunc("/campaigns/manage", permissionRequired(PermCampaignsManage, manageCampaigns))
+	http.HandleFunc("/applications", permissionRequired(PermApplicationsRead, listApplications))
+	http.HandleFunc("/applications/export", permissionRequired(PermApplicationsExport, exportApplications))
+	http.HandleFunc("/applications/documents", permissionRequired(PermDocumentsDownload, downloadDocuments))
+	http.HandleFunc("/applications/status", permissionRequired(PermApplicationsDecide, updateApplicationStatus))
+	http.HandleFunc("/applications/scorecards", permissionRequired(PermApplicationsReview, scorecardsHandler))
+	http.HandleFunc("/applications/comments", permissionRequired(PermApplicationsReview, commentsHandler))
+	http.HandleFunc("/applications/comments/history", permissionRequired(PermApplicationsReview, commentHistory))
+	http.HandleFunc("/applications/assignments", permissionRequired(PermApplicationsAssign, assignmentsHandler))
+	http.HandleFunc("/assignments/auto", permissionRequired(PermApplicationsAssign, autoAssignApplications))
+	http.HandleFunc("/assignments/queue", permissionRequired(PermApplicationsReview, myQueue))
+	http.HandleFunc("/assignments/workload", permissionRequired(PermApplicationsAssign, reviewerWorkload))
+	http.HandleFunc("/reviewers", permissionRequired(PermApplicationsAssign, reviewersHandler))
+	http.HandleFunc("/rubrics", permissionRequired(PermCampaignsManage, rubricsHandler))
+	http.HandleFunc("/candidates", permissionRequired(PermApplicationsRead, listCandidates))
+	http.HandleFunc("/candidates/applications", permissionRequired(PermApplicationsRead, candidateApplications))
+	http.HandleFunc("/subjects/delete", permissionRequired(PermSubjectsManage, deleteSubjects))
+	http.HandleFunc("/stats", permissionRequired(PermStatsRead, applicationStats))
+	http.HandleFunc("/applicant/magic-link", cookielessMiddleware(requestMagicLink))
+	http.HandleFunc("/applicant/login", corsMiddleware(applicantLogin))
+	http.HandleFunc("/applicant/logout", corsMiddleware(applicantLogout))
+	http.HandleFunc("/applicant/applications", applicantRequired(applicantApplications))
+	http.HandleFunc("/applicant/cv", applicantRequired(replaceCV))
+	http.HandleFunc("/applicant/contact", applicantRequired(updateContact))
+	http.HandleFunc("/applicant/withdraw", applicantRequired(withdrawApplication))
+	http.HandleFunc("/applicant/export", applicantRequired(applicantExport))
+	http.HandleFunc("/applicant/consent", applicantRequired(updateConsent))
+	http.HandleFunc("/applicant/erasure", applicantRequired(requestErasure))
+	http.HandleFunc("/applicant/erasure/confirm", cookielessMiddleware(confirmErasure))
+	http.HandleFunc("/webhooks", permissionRequired(PermWebhooksManage, webhooksHandler))
+	http.HandleFunc("/webhooks/deliveries", permissionRequired(PermWebhooksManage, webhookDeliveries))
+	http.HandleFunc("/webhooks/redeliver", permissionRequired(PermWebhooksManage, redeliverWebhook))
+	http.HandleFunc("/users", permissionRequired(PermUsersManage, usersHandler))
+	http.HandleFunc("/roles", permissionRequired(PermUsersManage, rolesHandler))
+	http.HandleFunc("/audit", permissionRequired(PermAuditRead, auditLog))
+	http.HandleFunc("/audit/verify", permissionRequired(PermAuditRead, verifyAuditLog))
+	http.HandleFunc("/retention/policies", permissionRequired(PermPrivacyManage, retentionPolicies))
+	http.HandleFunc("/retention/report", permissionRequired(PermPrivacyManage, retentionReport))
+	http.HandleFunc("/retention/run", permissionRequired(PermPrivacyManage, runRetentionNow))
+	http.HandleFunc("/retention/log", permissionRequired(PermPrivacyManage, purgeLog))
+	http.HandleFunc("/privacy/export", permissionRequired(PermPrivacyManage, privacyExport))
+	http.HandleFunc("/privacy/notices", permissionRequired(PermPrivacyManage, privacyNotices))
+	http.HandleFunc("/privacy/erasure", permissionRequired(PermPrivacyManage, privacyErasure))
+	http.HandleFunc("/interviews", permissionRequired(PermInterviewsManage, interviewsHandler))
+	http.HandleFunc("/interviews/slots", permissionRequired(PermInterviewsManage, interviewSlots))
+	http.HandleFunc("/interviews/cancel", permissionRequired(PermInterviewsManage, cancelInterviewHandler))
+	http.HandleFunc("/interviews/feed", permissionRequired(PermInterviewsManage, calendarFeedURL))
+	http.HandleFunc("/interviews/calendar.ics", calendarFeed)
+	http.HandleFunc("/interviews/booking", cookielessMiddleware(interviewBooking))
+	http.HandleFunc("/uploads/", permissionRequired(PermDocumentsDownload, serveFile))
 	http.Handle("/swagger/", httpSwagger.WrapHandler)
 
 	log.Println("API running on http://localhost:8080")
@@ -163,30 +271,16 @@ func main() {
 	}
 }
 
-func weeklyApplications(w http.ResponseWriter, r *http.Request) {
-	var count int
-	err := db.QueryRow(`
-		SELECT COUNT(*) FROM applications
-		WHERE created_at >= DATE_TRUNC('week', NOW())
-	`).Scan(&count)
-
-	if err != nil {
-		log.Printf("Error getting weekly applications: %v", err)
-		respondError(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	respondJSON(w, map[string]int{"count": count}, http.StatusOK)
-}
-
 // signup godoc
 // @Summary Create a new user
-// @Description Register a new user account
+// @Description Register a new user account. The role defaults to "user"; other roles need users:manage, except for the first account. The password must meet the password policy. The account can sign in once its email is verified through the link sent to it.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param body body object{username=string,email=string,password=string,role=string} true "User payload"
 // @Success 201
 // @Failure 400 {string} string
+// @Failure 403 {string} string
 // @Failure 409 {string} string
 // @Router /signup [post]
 
@@ -212,18 +306,61 @@ func signup(w http.ResponseWriter, r *http.Request) {
 		respondError(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
+	body.Email = normalizeEmail(body.Email)
+	if !validEmail(body.Email) {
+		respondError(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if err := checkPassword(body.Password, body.Username, body.Email); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+	if body.Role == "" {
+		body.Role = RoleUser
+	}
+	if !validRole(body.Role) {
+		respondError(w, "Unknown role", http.StatusBadRequest)
+		return
+	}
+	// Back-office roles are granted by user managers; only the very first
+	// account may pick one itself, so a fresh install can be bootstrapped.
+	if body.Role != RoleUser && !hasPermission(r, PermUsersManage) {
+		var managed bool
+		if err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM users WHERE role = ANY($1))
+		`, rolesWith(PermUsersManage)).Scan(&managed); err != nil {
+			log.Printf("Error checking user managers: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if managed {
+			respondError(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	hash, err := hashPassword(body.Password)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
 		respondError(w, "Server error", http.StatusInternalServerError)
 		return
 	}
 
-	_, err = db.Exec(`
-		INSERT INTO users (username, email, password_hash, role)
-		VALUES ($1, $2, $3, $4)
-	`, body.Username, body.Email, string(hash), body.Role)
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO users (username, email, password_hash, role, verification_sent_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id
+	`, body.Username, body.Email, hash, body.Role).Scan(&id)
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -234,12 +371,22 @@ func signup(w http.ResponseWriter, r *http.Request) {
 		respondError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	if err := sendVerificationEmail(tx, id, body.Email); err != nil {
+		log.Printf("Error queueing verification email: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 }
 
 // login godoc
 // @Summary Login
-// @Description Authenticate user and create session
+// @Description Authenticate user and create session. Accounts whose email is not verified yet are refused with 403. Users with two-factor authentication get two_factor_required instead, and finish with /login/2fa.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -270,11 +417,14 @@ func login(w http.ResponseWriter, r *http.Request) {
 
 	var id int
 	var hash, role, username string
+	var twoFactor, verified bool
 	err := db.QueryRow(`
-		SELECT id, password_hash, role, username FROM users WHERE username=$1
-	`, body.Username).Scan(&id, &hash, &role, &username)
+		SELECT id, password_hash, role, username, totp_enabled, email_verified_at IS NOT NULL
+		FROM users WHERE username=$1
+	`, body.Username).Scan(&id, &hash, &role, &username, &twoFactor, &verified)
 
 	if err == sql.ErrNoRows {
+		auditLogin(r, nil, body.Username, http.StatusUnauthorized)
 		respondError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	} else if err != nil {
@@ -283,21 +433,37 @@ func login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(body.Password)); err != nil {
+	ok, rehash := verifyPassword(hash, body.Password)
+	if !ok {
+		auditLogin(r, &id, username, http.StatusUnauthorized)
 		respondError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	if rehash {
+		upgradePasswordHash(id, hash, body.Password)
+	}
+	if !verified {
+		auditLogin(r, &id, username, http.StatusForbidden)
+		respondError(w, "Please verify your email address first", http.StatusForbidden)
+		return
+	}
 
-	session, _ := store.Get(r, "auth")
-	session.Values["user_id"] = id
-	session.Values["role"] = role
-	session.Values["username"] = username
+	if twoFactor {
+		if err := startTwoFactorLogin(w, r, id); err != nil {
+			log.Printf("Error saving session: %v", err)
+			respondError(w, "Session error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true, "two_factor_required": true}, http.StatusOK)
+		return
+	}
 
-	if err := session.Save(r, w); err != nil {
+	if err := startSession(w, r, id, role, username, false); err != nil {
 		log.Printf("Error saving session: %v", err)
 		respondError(w, "Session error", http.StatusInternalServerError)
 		return
 	}
+	auditLogin(r, &id, username, http.StatusOK)
 	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
 }
 
@@ -307,6 +473,16 @@ func logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if hash := currentSessionHash(r); hash != "" {
+		if _, err := db.Exec(`
+			UPDATE user_sessions SET revoked_at = NOW() WHERE token_hash=$1 AND revoked_at IS NULL
+		`, hash); err != nil {
+			log.Printf("Error revoking session: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	session, _ := store.Get(r, "auth")
 	session.Options.MaxAge = -1
 	if err := session.Save(r, w); err != nil {
@@ -319,29 +495,39 @@ func logout(w http.ResponseWriter, r *http.Request) {
 
 // me godoc
 // @Summary Current user info
-// @Description Returns current logged-in user
+// @Description Returns current logged-in user with the permissions of their role. two_factor_required means the role needs 2FA the session has not passed, so only the 2FA setup is reachable. csrf_token goes in the X-CSRF-Token header of requests that change something.
 // @Tags Auth
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /me [get]
 func me(w http.ResponseWriter, r *http.Request) {
+	token, err := csrfToken(w, r)
+	if err != nil {
+		log.Printf("Error saving session: %v", err)
+		respondError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+
 	session, err := store.Get(r, "auth")
 	if err != nil {
-		respondJSON(w, map[string]bool{"loggedIn": false}, http.StatusOK)
+		respondJSON(w, map[string]interface{}{"loggedIn": false, "csrf_token": token}, http.StatusOK)
 		return
 	}
 
-	role, ok := session.Values["role"].(string)
+	_, role, twoFactor, ok := currentSession(r)
 	if !ok {
-		respondJSON(w, map[string]bool{"loggedIn": false}, http.StatusOK)
+		respondJSON(w, map[string]interface{}{"loggedIn": false, "csrf_token": token}, http.StatusOK)
 		return
 	}
 
 	username, _ := session.Values["username"].(string)
 	respondJSON(w, map[string]interface{}{
-		"loggedIn": true,
-		"role":     role,
-		"username": username,
+		"loggedIn":            true,
+		"role":                role,
+		"permissions":         permissionsOf(role),
+		"username":            username,
+		"two_factor_required": !twoFactorSatisfied(r, role, twoFactor),
+		"csrf_token":          token,
 	}, http.StatusOK)
 }
 
@@ -356,8 +542,12 @@ func me(w http.ResponseWriter, r *http.Request) {
 // @Param cv formData file true "CV PDF"
 // @Param motivation formData file false "Motivation letter"
 // @Param subjects formData []string false "Subjects"
+// @Param consent formData bool true "Accepts the privacy notice"
+// @Param consent_version formData int true "Version of the accepted privacy notice"
+// @Param keep_profile formData bool false "Consents to keeping the profile for future campaigns"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {string} string
+// @Failure 409 {string} string
 // @Router /apply [post]
 func applyHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -370,56 +560,71 @@ func applyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	email := r.FormValue("email")
+	email := normalizeEmail(r.FormValue("email"))
 	if email == "" {
 		respondError(w, "Email is required", http.StatusBadRequest)
 		return
 	}
 
-	var exists bool
-	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM applications WHERE email=$1)`, email).Scan(&exists)
+	if !consentGiven(r.FormValue("consent")) {
+		respondError(w, "You must accept the privacy notice to apply", http.StatusBadRequest)
+		return
+	}
+	notice, err := currentNotice(db)
 	if err != nil {
-		log.Printf("Error checking email: %v", err)
+		log.Printf("Error fetching privacy notice: %v", err)
 		respondError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	if exists {
-		respondError(w, "Email already used", http.StatusConflict)
+	if v, err := strconv.Atoi(r.FormValue("consent_version")); err != nil || v != notice.Version {
+		respondError(w, "The privacy notice has changed, please read it again", http.StatusConflict)
 		return
 	}
 
-	savePDF := func(field string) (string, error) {
-		file, header, err := r.FormFile(field)
+	var campaignID sql.NullInt64
+	if v := r.FormValue("campaign_id"); v != "" {
+		id, err := strconv.Atoi(v)
 		if err != nil {
-			return "", err
+			respondError(w, "Invalid campaign", http.StatusBadRequest)
+			return
 		}
-		defer file.Close()
 
-		if err := os.MkdirAll("uploads", 0755); err != nil {
-			return "", err
+		var open bool
+		err = db.QueryRow(`SELECT `+campaignOpenCondition+` FROM campaigns WHERE id=$1`, id).Scan(&open)
+		if err == sql.ErrNoRows {
+			respondError(w, "Invalid campaign", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Printf("Error checking campaign: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
 		}
-
-		path := fmt.Sprintf("uploads/%d_%s", time.Now().UnixNano(), header.Filename)
-		dst, err := os.Create(path)
-		if err != nil {
-			return "", err
+		if !open {
+			respondError(w, "Campaign is not accepting applications", http.StatusBadRequest)
+			return
 		}
-		defer dst.Close()
+		campaignID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
 
-		if _, err := io.Copy(dst, file); err != nil {
-			return "", err
-		}
-		return path, nil
+	exists, err := hasOpenApplication(email, campaignID)
+	if err != nil {
+		log.Printf("Error checking email: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		respondError(w, "You already have an open application for this campaign", http.StatusConflict)
+		return
 	}
 
-	cvPath, err := savePDF("cv")
+	cvPath, err := savePDF(r, "cv")
 	if err != nil {
 		log.Printf("Error saving CV: %v", err)
 		respondError(w, "Failed to save CV", http.StatusInternalServerError)
 		return
 	}
 
-	motivationPath, _ := savePDF("motivation")
+	motivationPath, _ := savePDF(r, "motivation")
 
 	var startDate sql.NullTime
 	if v := r.FormValue("early_start_date"); v != "" {
@@ -429,15 +634,36 @@ func applyHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var candidateID int
+	err = tx.QueryRow(`
+		INSERT INTO candidates (email, full_name) VALUES ($1, $2)
+		ON CONFLICT (email) DO UPDATE SET full_name=EXCLUDED.full_name, updated_at=NOW()
+		RETURNING id`, email, r.FormValue("full_name")).Scan(&candidateID)
+	if err != nil {
+		log.Printf("Error saving candidate: %v", err)
+		respondError(w, "Failed to create application", http.StatusInternalServerError)
+		return
+	}
+
 	var appID int
-	err = db.QueryRow(`
+	err = tx.QueryRow(`
 		INSERT INTO applications (
 			full_name, gender, email, phone, university,
 			field_of_study, degree_level, application_type,
 			internship_duration, preferred_working_method,
-			start_date, cv_file_path, motivation_file_path
+			start_date, cv_file_path, motivation_file_path,
+			candidate_id, campaign_id,
+			consent_version, consented_at, keep_profile
 		)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,NOW(),$17)
 		RETURNING id`,
 		r.FormValue("full_name"),
 		r.FormValue("gender"),
@@ -452,20 +678,75 @@ func applyHandler(w http.ResponseWriter, r *http.Request) {
 		startDate,
 		cvPath,
 		motivationPath,
+		candidateID,
+		campaignID,
+		notice.Version,
+		consentGiven(r.FormValue("keep_profile")),
 	).Scan(&appID)
 
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			respondError(w, "You already have an open application for this campaign", http.StatusConflict)
+			return
+		}
 		log.Printf("Error creating application: %v", err)
 		respondError(w, "Failed to create application", http.StatusInternalServerError)
 		return
 	}
 
-	for _, subjectName := range r.Form["subjects"] {
-		var subjectID int
-		err := db.QueryRow(`SELECT id FROM subjects WHERE name=$1`, subjectName).Scan(&subjectID)
-		if err == nil {
-			db.Exec(`INSERT INTO application_subjects VALUES ($1,$2)`, appID, subjectID)
-		}
+	if _, err := tx.Exec(`
+		INSERT INTO application_subjects (application_id, subject_id)
+		SELECT $1, id FROM subjects WHERE name = ANY($2)
+		ON CONFLICT DO NOTHING`, appID, pq.Array(r.Form["subjects"])); err != nil {
+		log.Printf("Error linking subjects: %v", err)
+		respondError(w, "Failed to create application", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := autoAssign(tx, appID, assignmentStrategy); err != nil {
+		log.Printf("Error assigning reviewers: %v", err)
+		respondError(w, "Failed to create application", http.StatusInternalServerError)
+		return
+	}
+
+	if err := enqueueEmail(tx, email, "application_received", map[string]interface{}{
+		"ApplicationID": appID,
+		"FullName":      r.FormValue("full_name"),
+		"PortalURL":     appURL + "/portal",
+	}); err != nil {
+		log.Printf("Error queueing confirmation email: %v", err)
+		respondError(w, "Failed to create application", http.StatusInternalServerError)
+		return
+	}
+
+	var campaign interface{}
+	if campaignID.Valid {
+		campaign = campaignID.Int64
+	}
+	if err := enqueueWebhook(tx, EventApplicationCreated, "New application from "+r.FormValue("full_name"), map[string]interface{}{
+		"id":                       appID,
+		"candidate_id":             candidateID,
+		"campaign_id":              campaign,
+		"full_name":                r.FormValue("full_name"),
+		"email":                    email,
+		"university":               r.FormValue("university"),
+		"field_of_study":           r.FormValue("field_of_study"),
+		"degree_level":             r.FormValue("degree_level"),
+		"application_type":         r.FormValue("application_type"),
+		"internship_duration":      r.FormValue("internship_duration"),
+		"preferred_working_method": r.FormValue("preferred_working_method"),
+		"subjects":                 r.Form["subjects"],
+		"status":                   StatusSubmitted,
+	}); err != nil {
+		log.Printf("Error queueing webhook: %v", err)
+		respondError(w, "Failed to create application", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondError(w, "Failed to create application", http.StatusInternalServerError)
+		return
 	}
 
 	respondJSON(w, map[string]interface{}{
@@ -474,69 +755,94 @@ func applyHandler(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusCreated)
 }
 
+// savePDF stores the uploaded file of a multipart field under uploads/
+func savePDF(r *http.Request, field string) (string, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := os.MkdirAll("uploads", 0755); err != nil {
+		return "", err
+	}
+
+	path := fmt.Sprintf("uploads/%d_%s", time.Now().UnixNano(), header.Filename)
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
 // listApplications godoc
 // @Summary List applications
-// @Description Admin: list all applications
+// @Description Admin: list applications, optionally filtered. Supervisors only see the applications for the subjects they own, without phone and gender unless REDACT_SUPERVISOR_PII=false
 // @Tags Admin
 // @Produce json
 // @Security SessionAuth
+// @Param ids query string false "Comma-separated application IDs"
+// @Param status query string false "Status"
+// @Param campaign_id query int false "Campaign ID"
+// @Param candidate_id query int false "Candidate ID"
+// @Param gender query string false "Gender"
+// @Param university query string false "University"
+// @Param field_of_study query string false "Field of study"
+// @Param degree_level query string false "Degree level"
+// @Param application_type query string false "Application type"
+// @Param internship_duration query string false "Internship duration"
+// @Param preferred_working_method query string false "Preferred working method"
+// @Param subject query string false "Subject name"
+// @Param q query string false "Search in name and email"
+// @Param from query string false "Submitted on or after (YYYY-MM-DD)"
+// @Param to query string false "Submitted on or before (YYYY-MM-DD)"
+// @Param reviewer_id query int false "Assigned to this reviewer"
+// @Param assigned query bool false "Only applications with (true) or without (false) a reviewer"
+// @Param sort query string false "created_at (default, newest first) or score (highest first; scores hidden from the caller sort as unscored)"
 // @Success 200 {array} ApplicationResponse
 // @Failure 403 {string} string
 // @Router /applications [get]
 func listApplications(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query(`
-		SELECT id, full_name, email, gender, phone, university,
-		field_of_study, degree_level, application_type,
-		internship_duration, preferred_working_method,
-		start_date, created_at, cv_file_path, motivation_file_path
-		FROM applications ORDER BY created_at DESC
-	`)
+	where, args, err := applicationFilters(r.URL.Query())
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	scope := scopeOf(r)
+	where, args = scope.filter(where, args)
+
+	viewerID, _ := currentUserID(r)
+	order := "a.created_at DESC"
+	switch r.URL.Query().Get("sort") {
+	case "", "created_at":
+	case "score":
+		args = append(args, viewerID)
+		score := strings.ReplaceAll(applicationScoreExpr, "?", "$"+strconv.Itoa(len(args)))
+		order = score + " DESC NULLS LAST, " + order
+	default:
+		respondError(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	result, err := queryApplications(`
+		SELECT `+applicationColumns+`
+		FROM applications a`+where+` ORDER BY `+order, args...)
 	if err != nil {
 		log.Printf("Error fetching applications: %v", err)
 		respondError(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var result []ApplicationResponse
-
-	for rows.Next() {
-		var a ApplicationResponse
-		var start sql.NullTime
-		var created time.Time
-
-		if err := rows.Scan(
-			&a.ID, &a.FullName, &a.Email, &a.Gender, &a.Phone,
-			&a.University, &a.FieldOfStudy, &a.DegreeLevel,
-			&a.ApplicationType, &a.InternshipDuration,
-			&a.PreferredWorkingMethod, &start,
-			&created, &a.CVFilePath, &a.MotivationFilePath,
-		); err != nil {
-			log.Printf("Error scanning application: %v", err)
-			continue
-		}
+	scope.redactAll(result)
 
-		a.CreatedAt = created.Format("2006-01-02")
-		if start.Valid {
-			s := start.Time.Format("2006-01-02")
-			a.StartDate = &s
-		}
-
-		subRows, err := db.Query(`
-			SELECT s.name FROM subjects s
-			JOIN application_subjects a ON a.subject_id=s.id
-			WHERE a.application_id=$1`, a.ID)
-
-		if err == nil {
-			defer subRows.Close()
-			for subRows.Next() {
-				var name string
-				if err := subRows.Scan(&name); err == nil {
-					a.Subjects = append(a.Subjects, name)
-				}
-			}
-		}
-		result = append(result, a)
+	if err := attachScores(result, viewerID); err != nil {
+		log.Printf("Error fetching application scores: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
 	}
 
 	respondJSON(w, result, http.StatusOK)
@@ -544,7 +850,7 @@ func listApplications(w http.ResponseWriter, r *http.Request) {
 
 // subjectsHandler godoc
 // @Summary Manage subjects
-// @Description Get, create, or update subjects
+// @Description Get, create, or update subjects. Writes need subjects:manage
 // @Tags Subjects
 // @Produce json
 // @Success 200 {array} map[string]interface{}
@@ -588,7 +894,16 @@ func subjectsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		_, err := db.Exec(`INSERT INTO subjects (name) VALUES ($1)`, body.Name)
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var id int
+		err = tx.QueryRow(`INSERT INTO subjects (name) VALUES ($1) RETURNING id`, body.Name).Scan(&id)
 		if err != nil {
 			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 				respondError(w, "Subject already exists", http.StatusConflict)
@@ -598,6 +913,22 @@ func subjectsHandler(w http.ResponseWriter, r *http.Request) {
 			respondError(w, "Database error", http.StatusInternalServerError)
 			return
 		}
+
+		if err := enqueueWebhook(tx, EventSubjectCreated, "Subject created: "+body.Name, map[string]interface{}{
+			"id":   id,
+			"name": body.Name,
+		}); err != nil {
+			log.Printf("Error queueing webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "subjects", strconv.Itoa(id), nil, map[string]string{"name": body.Name})
 		respondJSON(w, map[string]bool{"success": true}, http.StatusCreated)
 
 	case http.MethodPut:
@@ -615,8 +946,25 @@ func subjectsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		_, err := db.Exec(`UPDATE subjects SET name=$1 WHERE id=$2`, body.Name, body.ID)
+		tx, err := db.Begin()
 		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var previous string
+		err = tx.QueryRow(`
+			UPDATE subjects s SET name=$1
+			FROM (SELECT id, name FROM subjects WHERE id=$2 FOR UPDATE) old
+			WHERE s.id = old.id
+			RETURNING old.name
+		`, body.Name, body.ID).Scan(&previous)
+		if err == sql.ErrNoRows {
+			respondError(w, "Subject not found", http.StatusNotFound)
+			return
+		} else if err != nil {
 			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 				respondError(w, "Subject name already exists", http.StatusConflict)
 				return
@@ -625,6 +973,24 @@ func subjectsHandler(w http.ResponseWriter, r *http.Request) {
 			respondError(w, "Database error", http.StatusInternalServerError)
 			return
 		}
+
+		if err := enqueueWebhook(tx, EventSubjectUpdated, "Subject renamed: "+previous+" → "+body.Name, map[string]interface{}{
+			"id":            body.ID,
+			"name":          body.Name,
+			"previous_name": previous,
+		}); err != nil {
+			log.Printf("Error queueing webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "subjects", strconv.Itoa(body.ID),
+			map[string]string{"name": previous}, map[string]string{"name": body.Name})
 		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
 
 	default:
@@ -685,14 +1051,37 @@ func deleteSubjects(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
+	deleted := map[string]string{}
 	if len(deletable) > 0 {
-		if _, err := tx.Exec(`
-			DELETE FROM subjects WHERE id = ANY($1)
-		`, pq.Array(deletable)); err != nil {
+		rows, err := tx.Query(`
+			DELETE FROM subjects WHERE id = ANY($1) RETURNING id, name
+		`, pq.Array(deletable))
+		if err != nil {
 			log.Printf("Error deleting subjects: %v", err)
 			respondError(w, "Database error", http.StatusInternalServerError)
 			return
 		}
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err == nil {
+				deleted[strconv.Itoa(id)] = name
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			log.Printf("Error deleting subjects: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := enqueueWebhook(tx, EventSubjectDeleted, fmt.Sprintf("%d subject(s) deleted", len(deletable)), map[string]interface{}{
+			"ids": deletable,
+		}); err != nil {
+			log.Printf("Error queueing webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -701,6 +1090,7 @@ func deleteSubjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditChange(r, "subjects", "", deleted, nil)
 	respondJSON(w, map[string]interface{}{
 		"deleted": deletable,
 		"in_use":  mapKeys(inUse),
@@ -718,18 +1108,51 @@ func serveFile(w http.ResponseWriter, r *http.Request) {
 		respondError(w, "Invalid file path", http.StatusBadRequest)
 		return
 	}
-	http.ServeFile(w, r, filePath)
+	appID, kind, err := scopeOf(r).documentOf(filePath)
+	if err == sql.ErrNoRows {
+		respondError(w, "File not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error checking document scope: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	http.ServeFile(sw, r, filePath)
+	// File names often carry the candidate's name, which must not outlive
+	// erasure in the audit log
+	recordAudit(r, sw.status, "documents.view", "applications", strconv.Itoa(appID), nil,
+		map[string]string{"document": kind})
 }
 
+// emailExists godoc
+// @Summary Check for an open application
+// @Description Reports whether the email already has an open (not rejected or withdrawn) application, optionally within one campaign
+// @Tags Applications
+// @Produce json
+// @Param email query string true "Email"
+// @Param campaign_id query int false "Campaign ID"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Router /email-exists [get]
 func emailExists(w http.ResponseWriter, r *http.Request) {
-	email := r.URL.Query().Get("email")
+	email := normalizeEmail(r.URL.Query().Get("email"))
 	if email == "" {
 		respondError(w, "Email parameter required", http.StatusBadRequest)
 		return
 	}
 
-	var exists bool
-	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM applications WHERE email=$1)`, email).Scan(&exists)
+	var campaignID sql.NullInt64
+	if v := r.URL.Query().Get("campaign_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, "Invalid campaign", http.StatusBadRequest)
+			return
+		}
+		campaignID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
+	exists, err := hasOpenApplication(email, campaignID)
 	if err != nil {
 		log.Printf("Error checking email: %v", err)
 		respondError(w, "Database error", http.StatusInternalServerError)
@@ -738,6 +1161,21 @@ func emailExists(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, map[string]bool{"exists": exists}, http.StatusOK)
 }
 
+// hasOpenApplication reports whether the candidate behind email already has
+// an application in the campaign that has not been rejected or withdrawn.
+func hasOpenApplication(email string, campaignID sql.NullInt64) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM applications a
+			JOIN candidates c ON c.id = a.candidate_id
+			WHERE c.email=$1
+			AND a.campaign_id IS NOT DISTINCT FROM $2
+			AND a.status <> ALL($3)
+		)`, email, campaignID, pq.Array(closedStatuses)).Scan(&exists)
+	return exists, err
+}
+
 func mapKeys(m map[int]bool) []int {
 	var keys []int
 	for k := range m {
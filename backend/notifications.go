@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//go:embed templates/email/*.tmpl
+var emailTemplateFS embed.FS
+
+// emailTemplates are keyed by file name without extension. Each file defines
+// a "subject" and a "body" template.
+var emailTemplates = loadEmailTemplates()
+
+const (
+	outboxBatchSize   = 20
+	outboxMaxAttempts = 8
+	outboxLease       = 5 * time.Minute
+)
+
+var (
+	outboxPollInterval = parseDuration(getEnv("OUTBOX_POLL_INTERVAL", "5s"))
+	hrDigestInterval   = parseDuration(getEnv("HR_DIGEST_INTERVAL", "24h"))
+	retentionInterval  = parseDuration(getEnv("RETENTION_INTERVAL", "24h"))
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so notifications can be
+// queued inside the transaction of the change that triggers them.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func loadEmailTemplates() map[string]*template.Template {
+	files, err := emailTemplateFS.ReadDir("templates/email")
+	if err != nil {
+		log.Fatal("Failed to read email templates:", err)
+	}
+
+	templates := map[string]*template.Template{}
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), ".tmpl")
+		templates[name] = template.Must(template.ParseFS(emailTemplateFS, "templates/email/"+f.Name()))
+	}
+	return templates
+}
+
+func parseDuration(v string) time.Duration {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid duration %q: %v", v, err)
+	}
+	return d
+}
+
+// enqueueEmail writes an email to the outbox; the worker renders and sends it.
+func enqueueEmail(ex execer, to, templateName string, data map[string]interface{}) error {
+	if _, ok := emailTemplates[templateName]; !ok {
+		return fmt.Errorf("unknown email template %q", templateName)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO email_outbox (recipient, template, payload)
+		VALUES ($1, $2, $3)
+	`, to, templateName, string(payload))
+	return err
+}
+
+// renderEmail executes a template. A calendar passed in the "ICS" field,
+// with its iTIP method in "ICSMethod", is returned as an attachment.
+func renderEmail(templateName string, payload []byte) (subject, body string, attachments []mailAttachment, err error) {
+	tmpl, ok := emailTemplates[templateName]
+	if !ok {
+		return "", "", nil, fmt.Errorf("unknown email template %q", templateName)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return "", "", nil, err
+	}
+
+	var sb, bb bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&sb, "subject", data); err != nil {
+		return "", "", nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&bb, "body", data); err != nil {
+		return "", "", nil, err
+	}
+
+	if ics, ok := data["ICS"].(string); ok && ics != "" {
+		method, _ := data["ICSMethod"].(string)
+		if method == "" {
+			method = "REQUEST"
+		}
+		attachments = append(attachments, mailAttachment{
+			Name:        "invite.ics",
+			ContentType: "text/calendar; charset=UTF-8; method=" + method,
+			Data:        []byte(ics),
+		})
+	}
+	return strings.TrimSpace(sb.String()), strings.TrimSpace(bb.String()) + "\n", attachments, nil
+}
+
+func statusLabel(status string) string {
+	return strings.ReplaceAll(status, "_", " ")
+}
+
+// statusEmail picks the candidate email for a status transition
+func statusEmail(appID int, fullName, previous, status string) (string, map[string]interface{}) {
+	data := map[string]interface{}{
+		"ApplicationID": appID,
+		"FullName":      fullName,
+		"PortalURL":     appURL + "/portal",
+	}
+	if status == StatusInterview {
+		data["BookingURL"] = bookingLink(appID)
+		return "interview_invitation", data
+	}
+	data["PreviousLabel"] = statusLabel(previous)
+	data["StatusLabel"] = statusLabel(status)
+	return "status_changed", data
+}
+
+// outboxBackoff is the delay before retry number attempts (1-based)
+func outboxBackoff(attempts int) time.Duration {
+	d := 30 * time.Second * time.Duration(math.Pow(2, float64(attempts-1)))
+	if d > 6*time.Hour {
+		d = 6 * time.Hour
+	}
+	return d
+}
+
+// runOutboxWorker delivers pending outbox emails until the process exits
+func runOutboxWorker() {
+	for {
+		n, err := deliverOutboxBatch()
+		if err != nil {
+			log.Printf("Error delivering outbox: %v", err)
+		}
+		if n < outboxBatchSize {
+			time.Sleep(outboxPollInterval)
+		}
+	}
+}
+
+// deliverOutboxBatch leases a batch of due emails so that several backend
+// instances never send the same message, then sends them one by one.
+func deliverOutboxBatch() (int, error) {
+	rows, err := db.Query(`
+		UPDATE email_outbox SET next_attempt_at = NOW() + make_interval(secs => $1)
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, template, payload, attempts
+	`, outboxLease.Seconds(), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	type message struct {
+		id        int
+		recipient string
+		template  string
+		payload   []byte
+		attempts  int
+	}
+	var batch []message
+	for rows.Next() {
+		var m message
+		if err := rows.Scan(&m.id, &m.recipient, &m.template, &m.payload, &m.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, m := range batch {
+		subject, body, attachments, err := renderEmail(m.template, m.payload)
+		if err == nil {
+			err = sendMail(m.recipient, subject, body, attachments...)
+		}
+
+		if err == nil {
+			if _, err := db.Exec(`
+				UPDATE email_outbox SET status='sent', attempts=attempts+1, sent_at=NOW(), last_error=NULL
+				WHERE id=$1`, m.id); err != nil {
+				log.Printf("Error marking email %d as sent: %v", m.id, err)
+			}
+			continue
+		}
+
+		attempts := m.attempts + 1
+		status := "pending"
+		if attempts >= outboxMaxAttempts {
+			status = "failed"
+		}
+		log.Printf("Error sending email %d (attempt %d): %v", m.id, attempts, err)
+		if _, dbErr := db.Exec(`
+			UPDATE email_outbox
+			SET status=$1, attempts=$2, last_error=$3, next_attempt_at = NOW() + make_interval(secs => $4)
+			WHERE id=$5`, status, attempts, err.Error(), outboxBackoff(attempts).Seconds(), m.id); dbErr != nil {
+			log.Printf("Error rescheduling email %d: %v", m.id, dbErr)
+		}
+	}
+	return len(batch), nil
+}
+
+// runHRDigest queues a summary of new applications for every user allowed to
+// decide on them once per hrDigestInterval.
+func runHRDigest() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := queueHRDigest(); err != nil {
+			log.Printf("Error queueing HR digest: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func queueHRDigest() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialise concurrent instances on the digest check
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('hr_digest'))`); err != nil {
+		return err
+	}
+
+	var last sql.NullTime
+	if err := tx.QueryRow(`
+		SELECT MAX(created_at) FROM email_outbox WHERE template='hr_digest'
+	`).Scan(&last); err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-hrDigestInterval)
+	if last.Valid {
+		if time.Since(last.Time) < hrDigestInterval {
+			return nil
+		}
+		since = last.Time
+	}
+
+	rows, err := tx.Query(`
+		SELECT a.full_name, a.university, a.field_of_study,
+		COALESCE(STRING_AGG(s.name, ', ' ORDER BY s.name), '')
+		FROM applications a
+		LEFT JOIN application_subjects aps ON aps.application_id = a.id
+		LEFT JOIN subjects s ON s.id = aps.subject_id
+		WHERE a.created_at > $1
+		GROUP BY a.id
+		ORDER BY a.created_at
+	`, since)
+	if err != nil {
+		return err
+	}
+
+	var apps []map[string]string
+	for rows.Next() {
+		var name, university, field, subjects string
+		if err := rows.Scan(&name, &university, &field, &subjects); err != nil {
+			rows.Close()
+			return err
+		}
+		apps = append(apps, map[string]string{
+			"full_name":      name,
+			"university":     university,
+			"field_of_study": field,
+			"subjects":       subjects,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		return nil
+	}
+
+	var recipients []string
+	if err := tx.QueryRow(`
+		SELECT COALESCE(ARRAY_AGG(email), '{}') FROM users WHERE role = ANY($1)
+	`, rolesWith(PermApplicationsDecide)).Scan(pq.Array(&recipients)); err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"Count":         len(apps),
+		"Since":         since.Format("2006-01-02 15:04"),
+		"Applications":  apps,
+		"BackofficeURL": appURL,
+	}
+	for _, to := range recipients {
+		if err := enqueueEmail(tx, to, "hr_digest", data); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// Argon2id parameters for new hashes. Hashes made with other parameters are
+// upgraded at the next login.
+const (
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 2
+	argon2KeyLen  = 32
+)
+
+const passwordResetTTL = time.Hour
+
+var (
+	minPasswordLength = parseInt(getEnv("PASSWORD_MIN_LENGTH", "10"))
+	// passwordHash is the algorithm new hashes use: argon2id, or bcrypt for
+	// anything else
+	passwordHash = getEnv("PASSWORD_HASH", HashBcrypt)
+	bcryptCost   = parseInt(getEnv("BCRYPT_COST", strconv.Itoa(bcrypt.DefaultCost)))
+)
+
+//go:embed data/common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords is the embedded list of passwords too common to accept
+var commonPasswords = func() map[string]bool {
+	m := map[string]bool{}
+	sc := bufio.NewScanner(strings.NewReader(commonPasswordList))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			m[strings.ToLower(line)] = true
+		}
+	}
+	return m
+}()
+
+// checkPassword enforces the password policy for the account with username
+// and email: a minimum length, no common password, and neither the username
+// nor the email inside it.
+func checkPassword(password, username, email string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return fmt.Errorf("Password must be at least %d characters", minPasswordLength)
+	}
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("Password is too common")
+	}
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	for _, part := range []string{strings.ToLower(username), local} {
+		if len(part) >= 3 && strings.Contains(lower, part) {
+			return errors.New("Password must not contain your username or email")
+		}
+	}
+	return nil
+}
+
+// hashPassword hashes password with the configured algorithm
+func hashPassword(password string) (string, error) {
+	if passwordHash == HashArgon2id {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+			argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	return string(hash), err
+}
+
+// verifyPassword reports whether password matches hash, and whether the hash
+// should be replaced because it was made with another algorithm or weaker
+// parameters than are configured now.
+func verifyPassword(hash, password string) (ok, rehash bool) {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, _ := bcrypt.Cost([]byte(hash))
+		return true, passwordHash == HashArgon2id || cost < bcryptCost
+	}
+
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+
+	got := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return false, false
+	}
+	return true, passwordHash != HashArgon2id ||
+		memory < argon2Memory || iterations < argon2Time || threads < argon2Threads
+}
+
+// newResetToken returns a random token for the reset link and the hash that
+// is stored in its place, so a database leak does not expose usable links.
+func newResetToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// forgotPassword godoc
+// @Summary Request a password reset
+// @Description Emails a single-use, expiring reset link to the account with this email. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body object{email=string} true "Email payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Router /password/forgot [post]
+func forgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	email := normalizeEmail(body.Email)
+	if email == "" {
+		respondError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := sendPasswordReset(email); err != nil {
+		log.Printf("Error requesting password reset: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// sendPasswordReset replaces any outstanding reset of the account with email
+// by a new one and queues its link. Unknown emails and single sign-on
+// accounts are silently ignored.
+func sendPasswordReset(email string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var to string
+	err = tx.QueryRow(`
+		SELECT id, email FROM users WHERE LOWER(email)=$1 AND oidc_subject IS NULL FOR UPDATE
+	`, email).Scan(&userID, &to)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	token, hash, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM password_resets WHERE user_id=$1 AND used_at IS NULL`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO password_resets (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, hash, time.Now().Add(passwordResetTTL)); err != nil {
+		return err
+	}
+	if err := enqueueEmail(tx, to, "password_reset", map[string]interface{}{
+		"Link":      appURL + "/reset-password?token=" + url.QueryEscape(token),
+		"ExpiresIn": "1 hour",
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resetPassword godoc
+// @Summary Reset a password
+// @Description Sets a new password with the token from the reset email. The token can only be used once, and every existing session of the account is signed out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body object{token=string,password=string} true "Reset payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Router /password/reset [post]
+func resetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Claiming the token in the same statement that checks it keeps two
+	// concurrent requests from both using it. A rejected password rolls the
+	// claim back, so the link can be tried again.
+	var userID int
+	var username, email string
+	err = tx.QueryRow(`
+		UPDATE password_resets p SET used_at = NOW()
+		FROM users u
+		WHERE u.id = p.user_id AND p.token_hash=$1 AND p.used_at IS NULL AND p.expires_at > NOW()
+		RETURNING u.id, u.username, u.email
+	`, hashToken(body.Token)).Scan(&userID, &username, &email)
+	if err == sql.ErrNoRows {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error claiming password reset: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := checkPassword(body.Password, username, email); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hash, err := hashPassword(body.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		respondError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET password_hash=$1 WHERE id=$2`, hash, userID); err != nil {
+		log.Printf("Error resetting password: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := revokeSessions(tx, userID, ""); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`DELETE FROM password_resets WHERE user_id=$1 AND used_at IS NULL`, userID); err != nil {
+		log.Printf("Error clearing password resets: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing password reset: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, http.StatusOK, "password.reset", "users", strconv.Itoa(userID), nil, nil)
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// upgradePasswordHash replaces the stored hash of a user who just signed in
+// with password by one made with the current settings. It only applies if
+// the hash is still the one that was checked, and failures only cost the
+// upgrade.
+func upgradePasswordHash(userID int, oldHash, password string) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("Error rehashing password: %v", err)
+		return
+	}
+	if _, err := db.Exec(`
+		UPDATE users SET password_hash=$1 WHERE id=$2 AND password_hash=$3
+	`, hash, userID, oldHash); err != nil {
+		log.Printf("Error upgrading password hash: %v", err)
+	}
+}
+
+// changePassword godoc
+// @Summary Change my password
+// @Description Sets a new password for the signed-in user after checking the current one. The new password must meet the password policy, and the user's other sessions are signed out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{current_password=string,new_password=string} true "Password payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Failure 401 {string} string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Router /password/change [post]
+func changePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	var username, email, oldHash string
+	if err := db.QueryRow(`
+		SELECT username, email, password_hash FROM users WHERE id=$1
+	`, userID).Scan(&username, &email, &oldHash); err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if ok, _ := verifyPassword(oldHash, body.CurrentPassword); !ok {
+		respondError(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+	if err := checkPassword(body.NewPassword, username, email); err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hash, err := hashPassword(body.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		respondError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	res, err := db.Exec(`
+		UPDATE users SET password_hash=$1 WHERE id=$2 AND password_hash=$3
+	`, hash, userID, oldHash)
+	if err != nil {
+		log.Printf("Error changing password: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		respondError(w, "Password was changed concurrently, please try again", http.StatusConflict)
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM password_resets WHERE user_id=$1 AND used_at IS NULL`, userID); err != nil {
+		log.Printf("Error clearing password resets: %v", err)
+	}
+	// Every other session is signed out; this one carries on
+	if err := revokeSessions(db, userID, currentSessionHash(r)); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
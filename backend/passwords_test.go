@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func argon2Hash(password string, memory, iterations uint32, threads uint8) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, iterations, memory, threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memory, iterations, threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestVerifyPassword(t *testing.T) {
+	defer func(algorithm string, cost int) {
+		passwordHash, bcryptCost = algorithm, cost
+	}(passwordHash, bcryptCost)
+
+	const password = "correct horse battery"
+	bcrypt4, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := argon2Hash(password, argon2Memory, argon2Time, argon2Threads)
+	weak := argon2Hash(password, argon2Memory, 1, argon2Threads)
+
+	tests := []struct {
+		name       string
+		algorithm  string
+		cost       int
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+	}{
+		{"bcrypt at configured cost", HashBcrypt, bcrypt.MinCost, string(bcrypt4), password, true, false},
+		{"bcrypt below configured cost", HashBcrypt, bcrypt.MinCost + 1, string(bcrypt4), password, true, true},
+		{"bcrypt when argon2id is configured", HashArgon2id, bcrypt.MinCost, string(bcrypt4), password, true, true},
+		{"bcrypt wrong password", HashArgon2id, bcrypt.MinCost, string(bcrypt4), "wrong", false, false},
+		{"argon2id with current parameters", HashArgon2id, bcrypt.MinCost, current, password, true, false},
+		{"argon2id with fewer iterations", HashArgon2id, bcrypt.MinCost, weak, password, true, true},
+		{"argon2id when bcrypt is configured", HashBcrypt, bcrypt.MinCost, current, password, true, true},
+		{"argon2id wrong password", HashArgon2id, bcrypt.MinCost, current, "wrong", false, false},
+		{"argon2id malformed", HashArgon2id, bcrypt.MinCost, "$argon2id$v=19$m=65536$salt$key", password, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passwordHash, bcryptCost = tt.algorithm, tt.cost
+			ok, rehash := verifyPassword(tt.hash, tt.password)
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("verifyPassword = (%v, %v), want (%v, %v)", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Permissions checked by the back-office endpoints
+const (
+	PermApplicationsRead   = "applications:read"
+	PermApplicationsReview = "applications:review"
+	PermApplicationsDecide = "applications:decide"
+	PermApplicationsAssign = "applications:assign"
+	PermApplicationsExport = "applications:export"
+	PermDocumentsDownload  = "documents:download"
+	PermInterviewsManage   = "interviews:manage"
+	PermSubjectsManage     = "subjects:manage"
+	PermCampaignsManage    = "campaigns:manage"
+	PermStatsRead          = "stats:read"
+	PermWebhooksManage     = "webhooks:manage"
+	PermUsersManage        = "users:manage"
+	PermAuditRead          = "audit:read"
+	PermPrivacyManage      = "privacy:manage"
+	PermTokensCreate       = "tokens:create"
+)
+
+// Roles a back-office user can hold. Accounts without one of these roles
+// have no permissions.
+const (
+	RoleAdmin      = "admin"
+	RoleHRManager  = "hr_manager"
+	RoleReviewer   = "reviewer"
+	RoleSupervisor = "supervisor"
+	RoleAuditor    = "auditor"
+	RoleUser       = "user"
+)
+
+var allPermissions = []string{
+	PermApplicationsRead, PermApplicationsReview, PermApplicationsDecide,
+	PermApplicationsAssign, PermApplicationsExport, PermDocumentsDownload,
+	PermInterviewsManage, PermSubjectsManage, PermCampaignsManage,
+	PermStatsRead, PermWebhooksManage, PermUsersManage, PermAuditRead,
+	PermPrivacyManage, PermTokensCreate,
+}
+
+// rolePermissions maps each role to what it may do
+var rolePermissions = map[string][]string{
+	RoleAdmin: allPermissions,
+	RoleHRManager: {
+		PermApplicationsRead, PermApplicationsReview, PermApplicationsDecide,
+		PermApplicationsAssign, PermApplicationsExport, PermDocumentsDownload,
+		PermInterviewsManage, PermSubjectsManage, PermCampaignsManage, PermStatsRead,
+	},
+	RoleReviewer: {
+		PermApplicationsRead, PermApplicationsReview, PermDocumentsDownload, PermInterviewsManage,
+	},
+	RoleSupervisor: {
+		PermApplicationsRead, PermApplicationsReview, PermDocumentsDownload,
+	},
+	RoleAuditor: {
+		PermApplicationsRead, PermStatsRead, PermAuditRead,
+	},
+	RoleUser: {},
+}
+
+// BackofficeUser is a user account as shown to user managers
+type BackofficeUser struct {
+	ID          int      `json:"id"`
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	TwoFactor   bool     `json:"two_factor"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+func validRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func permissionsOf(role string) []string {
+	perms := append([]string{}, rolePermissions[role]...)
+	sort.Strings(perms)
+	return perms
+}
+
+func roleHas(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// currentRole reads the role of the session user from the database, so role
+// changes apply immediately instead of at the next login. The session must
+// still be active in user_sessions, which also records the request as
+// activity; API tokens have their own expiry and revocation.
+func currentRole(r *http.Request) (int, string, bool) {
+	userID, role, _, ok := currentSession(r)
+	return userID, role, ok
+}
+
+// currentSession is currentRole, plus whether the session passed a second
+// factor
+func currentSession(r *http.Request) (userID int, role string, twoFactor, ok bool) {
+	userID, ok = currentUserID(r)
+	if !ok {
+		return 0, "", false, false
+	}
+	var err error
+	if apiTokenOf(r) != nil {
+		err = db.QueryRow(`SELECT role FROM users WHERE id=$1`, userID).Scan(&role)
+	} else {
+		role, twoFactor, err = touchSession(r, userID)
+	}
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error fetching user role: %v", err)
+		}
+		return 0, "", false, false
+	}
+	return userID, role, twoFactor, true
+}
+
+// hasPermission reports whether the session user holds perm, and the API
+// token of the request, if any, was granted it
+func hasPermission(r *http.Request, perm string) bool {
+	_, role, ok := currentRole(r)
+	return ok && roleHas(role, perm) && apiTokenOf(r).allows(perm)
+}
+
+// userRequired lets through any signed-in user, whatever their role. Their
+// mutating requests are audited.
+func userRequired(next http.HandlerFunc) http.HandlerFunc {
+	next = audited(next)
+	return corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := currentRole(r); !ok {
+			respondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// permissionRequired only lets through back-office users holding perm, who
+// signed in with a second factor if their role requires one, or API tokens
+// of such users granted perm. Their mutating requests are audited.
+func permissionRequired(perm string, next http.HandlerFunc) http.HandlerFunc {
+	next = audited(next)
+	return corsMiddleware(withAPIToken(func(w http.ResponseWriter, r *http.Request) {
+		_, role, twoFactor, ok := currentSession(r)
+		if !ok {
+			respondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !roleHas(role, perm) || !apiTokenOf(r).allows(perm) {
+			respondError(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if !twoFactorSatisfied(r, role, twoFactor) {
+			respondError(w, "Two-factor authentication required", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}))
+}
+
+// writePermissionRequired serves reads to anyone and passes the other
+// methods through permissionRequired(perm).
+func writePermissionRequired(perm string, next http.HandlerFunc) http.HandlerFunc {
+	public, guarded := corsMiddleware(next), permissionRequired(perm, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			public(w, r)
+			return
+		}
+		guarded(w, r)
+	}
+}
+
+// usersHandler godoc
+// @Summary Manage user roles
+// @Description Users with users:manage: list accounts with their permissions (GET) or change the role of another account (PUT), which signs it out everywhere
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int,role=string} false "Role payload (PUT)"
+// @Success 200 {array} BackofficeUser
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /users [get]
+// @Router /users [put]
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`SELECT id, username, email, role, totp_enabled, created_at FROM users ORDER BY username`)
+		if err != nil {
+			log.Printf("Error fetching users: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		users := []BackofficeUser{}
+		for rows.Next() {
+			var u BackofficeUser
+			var created time.Time
+			if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.TwoFactor, &created); err != nil {
+				log.Printf("Error scanning user: %v", err)
+				continue
+			}
+			u.Permissions = permissionsOf(u.Role)
+			u.CreatedAt = created.Format("2006-01-02")
+			users = append(users, u)
+		}
+		respondJSON(w, users, http.StatusOK)
+
+	case http.MethodPut:
+		var body struct {
+			ID   int    `json:"id"`
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if !validRole(body.Role) {
+			respondError(w, "Unknown role", http.StatusBadRequest)
+			return
+		}
+		if userID, _ := currentUserID(r); userID == body.ID {
+			respondError(w, "You cannot change your own role", http.StatusConflict)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var previous string
+		err = tx.QueryRow(`
+			UPDATE users u SET role=$1
+			FROM (SELECT id, role FROM users WHERE id=$2 FOR UPDATE) old
+			WHERE u.id = old.id
+			RETURNING old.role
+		`, body.Role, body.ID).Scan(&previous)
+		if err == sql.ErrNoRows {
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error updating user role: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		// The user signs in again to start working with the new role
+		if previous != body.Role {
+			if err := revokeSessions(tx, body.ID, ""); err != nil {
+				log.Printf("Error revoking sessions: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing user role: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "users", strconv.Itoa(body.ID),
+			map[string]string{"role": previous}, map[string]string{"role": body.Role})
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// rolesHandler godoc
+// @Summary List roles
+// @Description Users with users:manage: every role with its permissions
+// @Tags Users
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} map[string][]string
+// @Router /roles [get]
+func rolesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	roles := map[string][]string{}
+	for role := range rolePermissions {
+		roles[role] = permissionsOf(role)
+	}
+	respondJSON(w, roles, http.StatusOK)
+}
+
+// rolesWith lists the roles granting perm, as a query argument
+func rolesWith(perm string) interface{} {
+	var roles []string
+	for role := range rolePermissions {
+		if roleHas(role, perm) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return pq.Array(roles)
+}
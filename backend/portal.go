@@ -0,0 +1,511 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// magicLinkTTL is how long a login link stays valid; it works once
+const magicLinkTTL = 30 * time.Minute
+
+// applicantSessionTTL is how long an applicant stays signed in after using a
+// login link
+const applicantSessionTTL = 24 * time.Hour
+
+// editableCondition holds while a candidate may still change an application:
+// it is open and its campaign (aliased "cp") has not closed yet.
+var editableCondition = `a.status NOT IN ('` + strings.Join(closedStatuses, `', '`) + `')
+	AND (cp.closes_on IS NULL OR cp.closes_on >= CURRENT_DATE)`
+
+// PortalApplication is an application as shown to the candidate who submitted it
+type PortalApplication struct {
+	ApplicationResponse
+	Deadline *string `json:"deadline,omitempty"`
+	Editable bool    `json:"editable"`
+}
+
+func currentCandidate(r *http.Request) (int, bool) {
+	session, err := store.Get(r, "applicant")
+	if err != nil {
+		return 0, false
+	}
+	id, ok := session.Values["candidate_id"].(int)
+	return id, ok
+}
+
+// applicantSessionHash identifies the applicant_sessions row of the
+// request's cookie
+func applicantSessionHash(r *http.Request) string {
+	session, err := store.Get(r, "applicant")
+	if err != nil {
+		return ""
+	}
+	sid, _ := session.Values["sid"].(string)
+	if sid == "" {
+		return ""
+	}
+	return hashToken(sid)
+}
+
+// applicantRequired lets the request through while the session of its
+// applicant cookie is open on the server.
+func applicantRequired(next http.HandlerFunc) http.HandlerFunc {
+	return corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		candidateID, ok := currentCandidate(r)
+		if !ok {
+			respondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		result, err := db.Exec(`
+			UPDATE applicant_sessions SET last_seen_at = NOW()
+			WHERE candidate_id=$1 AND token_hash=$2 AND revoked_at IS NULL
+			AND created_at > NOW() - MAKE_INTERVAL(secs => $3)
+		`, candidateID, applicantSessionHash(r), applicantSessionTTL.Seconds())
+		if err != nil {
+			log.Printf("Error checking applicant session: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			respondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// ownedApplication loads the status and CV of one of the candidate's
+// applications and whether it can still be changed.
+func ownedApplication(candidateID, appID int) (status, cvPath string, editable bool, err error) {
+	err = db.QueryRow(`
+		SELECT a.status, a.cv_file_path, `+editableCondition+`
+		FROM applications a
+		LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+		WHERE a.id=$1 AND a.candidate_id=$2`, appID, candidateID).Scan(&status, &cvPath, &editable)
+	return
+}
+
+// requestMagicLink godoc
+// @Summary Request applicant login link
+// @Description Emails a single-use, expiring login link to the candidate, replacing any unused one. The response is the same whether or not the email is known.
+// @Tags Applicant
+// @Accept json
+// @Produce json
+// @Param body body object{email=string} true "Email payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Router /applicant/magic-link [post]
+func requestMagicLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	email := normalizeEmail(body.Email)
+	if email == "" {
+		respondError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := sendMagicLink(email); err != nil {
+		log.Printf("Error requesting magic link: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// sendMagicLink replaces any unused login link of the candidate with email
+// by a new one and queues it. Unknown emails are silently ignored.
+func sendMagicLink(email string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var candidateID int
+	err = tx.QueryRow(`SELECT id FROM candidates WHERE email=$1 FOR UPDATE`, email).Scan(&candidateID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	token, err := randomString()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM magic_links WHERE candidate_id=$1 AND used_at IS NULL`, candidateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO magic_links (candidate_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	`, candidateID, hashToken(token), time.Now().Add(magicLinkTTL)); err != nil {
+		return err
+	}
+	if err := enqueueEmail(tx, email, "magic_link", map[string]interface{}{
+		"Link":      appURL + "/portal?token=" + url.QueryEscape(token),
+		"ExpiresIn": "30 minutes",
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applicantLogin godoc
+// @Summary Applicant login
+// @Description Exchanges a magic-link token, which can only be used once, for an applicant session cookie. Like every request setting cookies, it needs the X-CSRF-Token header from /csrf.
+// @Tags Applicant
+// @Accept json
+// @Produce json
+// @Param body body object{token=string} true "Token payload"
+// @Success 200 {object} map[string]bool
+// @Failure 401 {string} string
+// @Router /applicant/login [post]
+func applicantLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Claiming the link in the statement that checks it keeps it single-use
+	var candidateID int
+	err := db.QueryRow(`
+		UPDATE magic_links SET used_at = NOW()
+		WHERE token_hash=$1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING candidate_id
+	`, hashToken(body.Token)).Scan(&candidateID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error claiming magic link: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	sid, err := randomString()
+	if err != nil {
+		log.Printf("Error starting applicant session: %v", err)
+		respondError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	// Ended sessions are only kept a while
+	if _, err := db.Exec(`
+		DELETE FROM applicant_sessions WHERE candidate_id=$1 AND last_seen_at < NOW() - INTERVAL '30 days'
+	`, candidateID); err != nil {
+		log.Printf("Error cleaning up applicant sessions: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO applicant_sessions (candidate_id, token_hash) VALUES ($1, $2)
+	`, candidateID, hashToken(sid)); err != nil {
+		log.Printf("Error starting applicant session: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	session, _ := store.Get(r, "applicant")
+	session.Values["sid"] = sid
+	session.Values["candidate_id"] = candidateID
+	if err := session.Save(r, w); err != nil {
+		log.Printf("Error saving session: %v", err)
+		respondError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+func applicantLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := db.Exec(`
+		UPDATE applicant_sessions SET revoked_at = NOW() WHERE token_hash=$1 AND revoked_at IS NULL
+	`, applicantSessionHash(r)); err != nil {
+		log.Printf("Error revoking applicant session: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	session, _ := store.Get(r, "applicant")
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		log.Printf("Error clearing session: %v", err)
+		respondError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// applicantApplications godoc
+// @Summary My applications
+// @Description Applicant: the signed-in candidate's applications with status and edit deadline
+// @Tags Applicant
+// @Produce json
+// @Success 200 {array} PortalApplication
+// @Failure 401 {string} string
+// @Router /applicant/applications [get]
+func applicantApplications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	candidateID, _ := currentCandidate(r)
+	apps, err := queryApplications(`
+		SELECT `+applicationColumns+`
+		FROM applications a WHERE a.candidate_id=$1
+		ORDER BY a.created_at DESC`, candidateID)
+	if err != nil {
+		log.Printf("Error fetching applications: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, cp.closes_on, `+editableCondition+`
+		FROM applications a
+		LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+		WHERE a.candidate_id=$1`, candidateID)
+	if err != nil {
+		log.Printf("Error fetching application deadlines: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type state struct {
+		deadline sql.NullTime
+		editable bool
+	}
+	states := map[int]state{}
+	for rows.Next() {
+		var id int
+		var s state
+		if err := rows.Scan(&id, &s.deadline, &s.editable); err == nil {
+			states[id] = s
+		}
+	}
+
+	result := []PortalApplication{}
+	for _, a := range apps {
+		s := states[a.ID]
+		p := PortalApplication{ApplicationResponse: a, Editable: s.editable}
+		if s.deadline.Valid {
+			d := s.deadline.Time.Format("2006-01-02")
+			p.Deadline = &d
+		}
+		result = append(result, p)
+	}
+	respondJSON(w, result, http.StatusOK)
+}
+
+// replaceCV godoc
+// @Summary Replace CV
+// @Description Applicant: upload a new CV for an application until its campaign closes
+// @Tags Applicant
+// @Accept multipart/form-data
+// @Produce json
+// @Param application_id formData int true "Application ID"
+// @Param cv formData file true "CV PDF"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /applicant/cv [post]
+func replaceCV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(20 << 20); err != nil {
+		respondError(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	appID, err := strconv.Atoi(r.FormValue("application_id"))
+	if err != nil {
+		respondError(w, "Invalid application id", http.StatusBadRequest)
+		return
+	}
+
+	candidateID, _ := currentCandidate(r)
+	_, oldPath, editable, err := ownedApplication(candidateID, appID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching application: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !editable {
+		respondError(w, "Application can no longer be changed", http.StatusConflict)
+		return
+	}
+
+	cvPath, err := savePDF(r, "cv")
+	if err != nil {
+		log.Printf("Error saving CV: %v", err)
+		respondError(w, "Failed to save CV", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.Exec(`UPDATE applications SET cv_file_path=$1 WHERE id=$2`, cvPath, appID); err != nil {
+		os.Remove(cvPath)
+		log.Printf("Error updating CV: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := os.Remove(oldPath); err != nil {
+		log.Printf("Error removing old CV %s: %v", oldPath, err)
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// updateContact godoc
+// @Summary Update contact info
+// @Description Applicant: change name and phone on the candidate profile and on applications that are still editable
+// @Tags Applicant
+// @Accept json
+// @Produce json
+// @Param body body object{full_name=string,phone=string} true "Contact payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Router /applicant/contact [put]
+func updateContact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		FullName string `json:"full_name"`
+		Phone    string `json:"phone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	body.FullName = strings.TrimSpace(body.FullName)
+	if body.FullName == "" {
+		respondError(w, "Full name is required", http.StatusBadRequest)
+		return
+	}
+
+	candidateID, _ := currentCandidate(r)
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE candidates SET full_name=$1, updated_at=NOW() WHERE id=$2
+	`, body.FullName, candidateID); err != nil {
+		log.Printf("Error updating candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE applications SET full_name=$1, phone=$2
+		WHERE id IN (
+			SELECT a.id FROM applications a
+			LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+			WHERE a.candidate_id=$3 AND `+editableCondition+`
+		)`, body.FullName, body.Phone, candidateID); err != nil {
+		log.Printf("Error updating applications: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// withdrawApplication godoc
+// @Summary Withdraw application
+// @Description Applicant: withdraw an open application
+// @Tags Applicant
+// @Accept json
+// @Produce json
+// @Param body body object{id=int} true "Application payload"
+// @Success 200 {object} map[string]bool
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /applicant/withdraw [post]
+func withdrawApplication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	candidateID, _ := currentCandidate(r)
+	status, _, _, err := ownedApplication(candidateID, body.ID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching application: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if isClosedStatus(status) {
+		respondError(w, "Application is already closed", http.StatusConflict)
+		return
+	}
+
+	if _, err := changeStatus(body.ID, StatusWithdrawn); err == errApplicationAnonymized {
+		respondError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error withdrawing application: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	erasurePurpose = "data-erasure"
+	erasureTTL     = 24 * time.Hour
+)
+
+// ExportedEmail is an email sent, or about to be sent, to the candidate
+type ExportedEmail struct {
+	Template  string  `json:"template"`
+	Status    string  `json:"status"`
+	CreatedAt string  `json:"created_at"`
+	SentAt    *string `json:"sent_at"`
+}
+
+// DataExport is data.json in the export bundle: everything held about a
+// candidate. The uploaded documents sit next to it in the archive.
+type DataExport struct {
+	GeneratedAt  string                `json:"generated_at"`
+	Candidate    Candidate             `json:"candidate"`
+	Applications []ApplicationResponse `json:"applications"`
+	Interviews   []Interview           `json:"interviews"`
+	Emails       []ExportedEmail       `json:"emails"`
+	Documents    []string              `json:"documents"`
+}
+
+// candidateEmails lists every address the candidate used, lower-cased
+func candidateEmails(q queryer, candidateID int) ([]string, error) {
+	var emails []string
+	err := q.QueryRow(`
+		SELECT ARRAY(
+			SELECT LOWER(c.email) FROM candidates c WHERE c.id=$1
+			UNION SELECT LOWER(a.email) FROM applications a WHERE a.candidate_id=$1 AND a.email <> ''
+		)
+	`, candidateID).Scan(pq.Array(&emails))
+	return emails, err
+}
+
+// collectDataExport gathers the export of a candidate, and the documents to
+// bundle with it keyed by their name in the archive. It returns
+// sql.ErrNoRows for an unknown candidate.
+func collectDataExport(candidateID int) (DataExport, map[string]string, error) {
+	export := DataExport{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Interviews:  []Interview{},
+		Emails:      []ExportedEmail{},
+		Documents:   []string{},
+	}
+	docs := map[string]string{}
+
+	c, err := scanCandidate(db.QueryRow(`SELECT `+candidateColumns+` FROM candidates c WHERE c.id=$1`, candidateID))
+	if err != nil {
+		return export, nil, err
+	}
+	export.Candidate = c
+
+	export.Applications, err = queryApplications(`
+		SELECT `+applicationColumns+`
+		FROM applications a WHERE a.candidate_id=$1
+		ORDER BY a.created_at, a.id`, candidateID)
+	if err != nil {
+		return export, nil, err
+	}
+	for _, a := range export.Applications {
+		paths := map[string]string{"cv": a.CVFilePath}
+		if a.MotivationFilePath != nil {
+			paths["motivation"] = *a.MotivationFilePath
+		}
+		for _, kind := range []string{"cv", "motivation"} {
+			if path, ok := uploadedFile(paths[kind]); ok {
+				name := "documents/" + strconv.Itoa(a.ID) + "/" + kind + strings.ToLower(filepath.Ext(path))
+				docs[name] = path
+				export.Documents = append(export.Documents, name)
+			}
+		}
+	}
+
+	rows, err := db.Query(`SELECT `+interviewColumns+interviewJoins+`
+		WHERE a.candidate_id=$1 ORDER BY s.starts_at, i.id`, candidateID)
+	if err != nil {
+		return export, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		iv, err := scanInterview(rows)
+		if err != nil {
+			return export, nil, err
+		}
+		export.Interviews = append(export.Interviews, iv)
+	}
+	if err := rows.Err(); err != nil {
+		return export, nil, err
+	}
+
+	emails, err := candidateEmails(db, candidateID)
+	if err != nil {
+		return export, nil, err
+	}
+	mails, err := db.Query(`
+		SELECT template, status, created_at, sent_at FROM email_outbox
+		WHERE LOWER(recipient) = ANY($1) ORDER BY created_at, id
+	`, pq.Array(emails))
+	if err != nil {
+		return export, nil, err
+	}
+	defer mails.Close()
+	for mails.Next() {
+		var m ExportedEmail
+		var created time.Time
+		var sent sql.NullTime
+		if err := mails.Scan(&m.Template, &m.Status, &created, &sent); err != nil {
+			return export, nil, err
+		}
+		m.CreatedAt = created.Format(time.RFC3339)
+		if sent.Valid {
+			s := sent.Time.Format(time.RFC3339)
+			m.SentAt = &s
+		}
+		export.Emails = append(export.Emails, m)
+	}
+	return export, docs, mails.Err()
+}
+
+// sendDataExport answers with the export bundle of a candidate: data.json
+// and the uploaded documents in a ZIP archive.
+func sendDataExport(w http.ResponseWriter, r *http.Request, candidateID int) {
+	export, docs, err := collectDataExport(candidateID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error collecting data export: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="data-export-`+time.Now().Format("20060102")+`.zip"`)
+	recordAudit(r, http.StatusOK, "data.export", "candidates", strconv.Itoa(candidateID), nil, nil)
+
+	// Headers are already sent, so errors from here on can only be logged.
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("data.json")
+	if err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(export)
+	}
+	if err != nil {
+		log.Printf("Error writing data export: %v", err)
+		return
+	}
+	for _, name := range export.Documents {
+		if err := addZipFile(zw, name, docs[name]); err != nil {
+			log.Printf("Error adding %s to data export: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Error finishing data export: %v", err)
+	}
+}
+
+// eraseCandidate deletes a candidate with their applications, and through
+// them the subject links, interviews, scores, notes and assignments, plus
+// the emails and webhook deliveries that carry their address. Each
+// application is recorded in the purge log. It returns the documents to
+// delete after commit, or sql.ErrNoRows for an unknown candidate.
+func eraseCandidate(tx *sql.Tx, candidateID int, actorID *int) (int, []string, error) {
+	if err := tx.QueryRow(`SELECT id FROM candidates WHERE id=$1 FOR UPDATE`, candidateID).Scan(&candidateID); err != nil {
+		return 0, nil, err
+	}
+	emails, err := candidateEmails(tx, candidateID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, cv_file_path, COALESCE(motivation_file_path, '')
+		FROM applications WHERE candidate_id=$1 FOR UPDATE
+	`, candidateID)
+	if err != nil {
+		return 0, nil, err
+	}
+	type erased struct {
+		id   int
+		docs []string
+	}
+	var apps []erased
+	var docs []string
+	for rows.Next() {
+		var a erased
+		var cv, motivation string
+		if err := rows.Scan(&a.id, &cv, &motivation); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		for _, path := range []string{cv, motivation} {
+			if clean, ok := uploadedFile(path); ok {
+				a.docs = append(a.docs, clean)
+			}
+		}
+		docs = append(docs, a.docs...)
+		apps = append(apps, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	for _, a := range apps {
+		if _, err := tx.Exec(`
+			INSERT INTO purge_log (application_id, candidate_id, reason, documents, actor_id)
+			VALUES ($1, $2, $3, $4, $5)
+		`, a.id, candidateID, PurgeErasure, len(a.docs), actorID); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM email_outbox WHERE LOWER(recipient) = ANY($1)`,
+		`DELETE FROM webhook_deliveries WHERE LOWER(payload->'data'->>'email') = ANY($1)`,
+	} {
+		if _, err := tx.Exec(stmt, pq.Array(emails)); err != nil {
+			return 0, nil, err
+		}
+	}
+	for _, stmt := range []string{
+		`DELETE FROM applications WHERE candidate_id=$1`,
+		`DELETE FROM candidates WHERE id=$1`,
+	} {
+		if _, err := tx.Exec(stmt, candidateID); err != nil {
+			return 0, nil, err
+		}
+	}
+	return len(apps), docs, nil
+}
+
+// performErasure erases a candidate and their stored documents
+func performErasure(candidateID int, actorID *int) (int, int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	apps, docs, err := eraseCandidate(tx, candidateID, actorID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	removeDocuments(docs)
+	return apps, len(docs), nil
+}
+
+// applicantExport godoc
+// @Summary Export my data
+// @Description Applicant: a ZIP with data.json, holding everything stored about the signed-in candidate, and the documents they uploaded
+// @Tags Applicant
+// @Produce application/zip
+// @Success 200 {file} file
+// @Failure 401 {string} string
+// @Router /applicant/export [get]
+func applicantExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	candidateID, _ := currentCandidate(r)
+	sendDataExport(w, r, candidateID)
+}
+
+// requestErasure godoc
+// @Summary Request erasure of my data
+// @Description Applicant: emails the signed-in candidate a link to confirm the erasure of all their data
+// @Tags Applicant
+// @Produce json
+// @Success 200 {object} map[string]bool
+// @Failure 401 {string} string
+// @Router /applicant/erasure [post]
+func requestErasure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	candidateID, _ := currentCandidate(r)
+	var email string
+	if err := db.QueryRow(`SELECT email FROM candidates WHERE id=$1`, candidateID).Scan(&email); err == sql.ErrNoRows {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := enqueueEmail(db, email, "erasure_request", map[string]interface{}{
+		"Link":      appURL + "/portal/erase?token=" + url.QueryEscape(signToken(erasurePurpose, candidateID, erasureTTL)),
+		"ExpiresIn": "24 hours",
+	}); err != nil {
+		log.Printf("Error queueing erasure confirmation: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, http.StatusOK, "erasure.request", "candidates", strconv.Itoa(candidateID), nil, nil)
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// confirmErasure godoc
+// @Summary Confirm erasure of my data
+// @Description Erases the candidate named by the token from the confirmation email, with their applications and documents, and ends their portal session
+// @Tags Applicant
+// @Accept json
+// @Produce json
+// @Param body body object{token=string} true "Token payload"
+// @Success 200 {object} map[string]int
+// @Failure 401 {string} string
+// @Router /applicant/erasure/confirm [post]
+func confirmErasure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	candidateID, err := verifyToken(erasurePurpose, body.Token)
+	if err != nil {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	}
+
+	apps, docs, err := performErasure(candidateID, nil)
+	if err == sql.ErrNoRows {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error erasing candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, http.StatusOK, "erasure", "candidates", strconv.Itoa(candidateID), nil,
+		map[string]int{"applications": apps, "documents": docs})
+
+	session, _ := store.Get(r, "applicant")
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		log.Printf("Error clearing session: %v", err)
+	}
+	respondJSON(w, map[string]int{"applications": apps, "documents": docs}, http.StatusOK)
+}
+
+// privacyExport godoc
+// @Summary Export a candidate's data
+// @Description Users with privacy:manage: the same bundle a candidate gets from /applicant/export, for an email address
+// @Tags Privacy
+// @Produce application/zip
+// @Security SessionAuth
+// @Param email query string true "Candidate email"
+// @Success 200 {file} file
+// @Failure 404 {string} string
+// @Router /privacy/export [get]
+func privacyExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var candidateID int
+	err := db.QueryRow(`SELECT id FROM candidates WHERE email=$1`, normalizeEmail(r.URL.Query().Get("email"))).Scan(&candidateID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	sendDataExport(w, r, candidateID)
+}
+
+// privacyErasure godoc
+// @Summary Erase a candidate's data
+// @Description Users with privacy:manage: erase everything held about an email address, with its applications, subject links and documents. confirm must repeat the email.
+// @Tags Privacy
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{email=string,confirm=string} true "Erasure payload"
+// @Success 200 {object} map[string]int
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /privacy/erasure [post]
+func privacyErasure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Email   string `json:"email"`
+		Confirm string `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	email := normalizeEmail(body.Email)
+	if email == "" || normalizeEmail(body.Confirm) != email {
+		respondError(w, "confirm must repeat the email", http.StatusBadRequest)
+		return
+	}
+
+	var candidateID int
+	err := db.QueryRow(`SELECT id FROM candidates WHERE email=$1`, email).Scan(&candidateID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var actorID *int
+	if id, ok := currentUserID(r); ok {
+		actorID = &id
+	}
+	apps, docs, err := performErasure(candidateID, actorID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Candidate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error erasing candidate: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	result := map[string]int{"applications": apps, "documents": docs}
+	auditChange(r, "candidates", strconv.Itoa(candidateID), nil, result)
+	respondJSON(w, result, http.StatusOK)
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Retention periods start when the application's campaign closed, or when
+// it was submitted. Applications outside any campaign, or in one that never
+// closes, count from their submission under either basis.
+const (
+	RetentionFromCampaignClose = "campaign_close"
+	RetentionFromSubmission    = "submission"
+)
+
+// Purge reasons recorded in the purge log
+const (
+	PurgeRetention = "retention"
+	PurgeErasure   = "erasure"
+)
+
+// RetentionPolicy anonymizes applications in one of Statuses once Months
+// have passed since Basis.
+type RetentionPolicy struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Statuses  []string `json:"statuses"`
+	Basis     string   `json:"basis"`
+	Months    int      `json:"months"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// DueApplication is an application a policy would anonymize now
+type DueApplication struct {
+	ID         int    `json:"id"`
+	Status     string `json:"status"`
+	CampaignID *int   `json:"campaign_id"`
+	CreatedAt  string `json:"created_at"`
+	Documents  int    `json:"documents"`
+}
+
+// RetentionReport is the dry run of one policy
+type RetentionReport struct {
+	Policy       RetentionPolicy  `json:"policy"`
+	Count        int              `json:"count"`
+	Applications []DueApplication `json:"applications"`
+}
+
+// PurgeEntry is one anonymized or erased application in the purge log
+type PurgeEntry struct {
+	ID            int64  `json:"id"`
+	ApplicationID int    `json:"application_id"`
+	CandidateID   *int   `json:"candidate_id"`
+	PolicyID      *int   `json:"policy_id"`
+	Policy        string `json:"policy"`
+	Reason        string `json:"reason"`
+	Documents     int    `json:"documents"`
+	Actor         string `json:"actor"`
+	PurgedAt      string `json:"purged_at"`
+}
+
+// errApplicationAnonymized refuses changes to an anonymized application: it
+// is kept for statistics only and its candidate can no longer be reached.
+var errApplicationAnonymized = errors.New("Application has been anonymized")
+
+// retentionDue matches the applications over "a" a policy covers, with its
+// statuses, basis and months bound to $1, $2 and $3. Candidates who agreed
+// to be kept for future campaigns are left alone until they withdraw it.
+const retentionDue = `a.anonymized_at IS NULL AND NOT a.keep_profile AND a.status = ANY($1)
+	AND CASE WHEN $2::TEXT = 'submission' THEN a.created_at
+		ELSE COALESCE((SELECT c.closes_on::TIMESTAMPTZ + INTERVAL '1 day' FROM campaigns c WHERE c.id = a.campaign_id), a.created_at)
+	END < NOW() - MAKE_INTERVAL(months => $3::INT)`
+
+func validRetentionBasis(basis string) bool {
+	return basis == RetentionFromCampaignClose || basis == RetentionFromSubmission
+}
+
+func loadRetentionPolicies(where string, args ...interface{}) ([]RetentionPolicy, error) {
+	rows, err := db.Query(`
+		SELECT id, name, statuses, basis, months, active, created_at
+		FROM retention_policies`+where+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []RetentionPolicy{}
+	for rows.Next() {
+		var p RetentionPolicy
+		var created time.Time
+		if err := rows.Scan(&p.ID, &p.Name, pq.Array(&p.Statuses), &p.Basis, &p.Months, &p.Active, &created); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = created.Format("2006-01-02")
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// dueApplications lists what p would anonymize now, oldest first
+func dueApplications(p RetentionPolicy) ([]DueApplication, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.status, a.campaign_id, a.created_at,
+		(CASE WHEN a.cv_file_path <> '' THEN 1 ELSE 0 END) +
+		(CASE WHEN COALESCE(a.motivation_file_path, '') <> '' THEN 1 ELSE 0 END)
+		FROM applications a
+		WHERE `+retentionDue+`
+		ORDER BY a.created_at, a.id
+	`, pq.Array(p.Statuses), p.Basis, p.Months)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	apps := []DueApplication{}
+	for rows.Next() {
+		var a DueApplication
+		var campaignID sql.NullInt64
+		var created time.Time
+		if err := rows.Scan(&a.ID, &a.Status, &campaignID, &created, &a.Documents); err != nil {
+			return nil, err
+		}
+		if campaignID.Valid {
+			id := int(campaignID.Int64)
+			a.CampaignID = &id
+		}
+		a.CreatedAt = created.Format("2006-01-02")
+		apps = append(apps, a)
+	}
+	return apps, rows.Err()
+}
+
+// anonymizeApplication blanks the personal fields of an application and the
+// free text written about it, and logs the purge. Status, subjects, campaign
+// and the academic profile stay, so the statistics are unchanged. Once the
+// candidate has no other application left, their identity and emails go
+// too. It returns the documents to delete after commit, or sql.ErrNoRows if
+// the application is unknown or already anonymized.
+func anonymizeApplication(tx *sql.Tx, appID int, policyID *int, reason string, actorID *int) ([]string, error) {
+	var cv, email string
+	var motivation sql.NullString
+	var candidateID int
+	if err := tx.QueryRow(`
+		UPDATE applications a SET full_name='Anonymized', email='', phone='', gender='',
+			cv_file_path='', motivation_file_path=NULL, anonymized_at=NOW()
+		FROM (SELECT id, cv_file_path, motivation_file_path, candidate_id, email
+			FROM applications WHERE id=$1 AND anonymized_at IS NULL FOR UPDATE) old
+		WHERE a.id = old.id
+		RETURNING old.cv_file_path, old.motivation_file_path, old.candidate_id, old.email
+	`, appID).Scan(&cv, &motivation, &candidateID, &email); err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM comment_revisions WHERE comment_id IN (SELECT id FROM comments WHERE application_id=$1)`,
+		`UPDATE comments SET body='', deleted_at=COALESCE(deleted_at, NOW()) WHERE application_id=$1`,
+		`UPDATE scorecards SET comment='' WHERE application_id=$1`,
+		// booked_by only says who booked; anything else there may name the candidate
+		`UPDATE interviews SET booked_by='' WHERE application_id=$1 AND booked_by NOT IN ('hr', 'candidate')`,
+	} {
+		if _, err := tx.Exec(stmt, appID); err != nil {
+			return nil, err
+		}
+	}
+	// Webhook deliveries about the application carry the name and email
+	if _, err := tx.Exec(`
+		DELETE FROM webhook_deliveries WHERE event = ANY($1) AND payload->'data'->>'id' = $2::TEXT
+	`, pq.Array([]string{EventApplicationCreated, EventApplicationStatusChanged}), appID); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE candidates SET email='anonymized-' || id || '@invalid', full_name='Anonymized', updated_at=NOW()
+		WHERE id=$1 AND NOT EXISTS (
+			SELECT 1 FROM applications WHERE candidate_id=$1 AND anonymized_at IS NULL
+		)
+	`, candidateID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		for _, stmt := range []string{
+			`DELETE FROM email_outbox WHERE LOWER(recipient) = LOWER($1)`,
+			`DELETE FROM webhook_deliveries WHERE LOWER(payload->'data'->>'email') = LOWER($1)`,
+		} {
+			if _, err := tx.Exec(stmt, email); err != nil {
+				return nil, err
+			}
+		}
+		// Nor can they sign in to the portal any more
+		for _, stmt := range []string{
+			`DELETE FROM applicant_sessions WHERE candidate_id=$1`,
+			`DELETE FROM magic_links WHERE candidate_id=$1`,
+		} {
+			if _, err := tx.Exec(stmt, candidateID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var docs []string
+	for _, path := range []string{cv, motivation.String} {
+		if clean, ok := uploadedFile(path); ok {
+			docs = append(docs, clean)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO purge_log (application_id, candidate_id, policy_id, reason, documents, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, appID, candidateID, policyID, reason, len(docs), actorID); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// removeDocuments deletes stored uploads once their application is gone.
+// Files already missing are fine.
+func removeDocuments(docs []string) {
+	for _, path := range docs {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error deleting document %s: %v", path, err)
+		}
+	}
+}
+
+// applyRetention anonymizes everything p covers, one application per
+// transaction so a failure only skips that application.
+func applyRetention(p RetentionPolicy, actorID *int) (int, error) {
+	due, err := dueApplications(p)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, a := range due {
+		tx, err := db.Begin()
+		if err != nil {
+			return purged, err
+		}
+		// The application may have moved on since the report, check again
+		// under lock.
+		var docs []string
+		err = tx.QueryRow(`
+			SELECT a.id FROM applications a WHERE a.id=$4 AND `+retentionDue+` FOR UPDATE
+		`, pq.Array(p.Statuses), p.Basis, p.Months, a.ID).Scan(&a.ID)
+		if err == nil {
+			docs, err = anonymizeApplication(tx, a.ID, &p.ID, PurgeRetention, actorID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			log.Printf("Error anonymizing application %d: %v", a.ID, err)
+			continue
+		}
+		removeDocuments(docs)
+		purged++
+	}
+	return purged, nil
+}
+
+// runRetention applies the active policies once per retentionInterval
+func runRetention() {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		policies, err := loadRetentionPolicies(` WHERE active`)
+		if err != nil {
+			log.Printf("Error fetching retention policies: %v", err)
+		}
+		for _, p := range policies {
+			n, err := applyRetention(p, nil)
+			if err != nil {
+				log.Printf("Error applying retention policy %q: %v", p.Name, err)
+			}
+			if n > 0 {
+				log.Printf("Retention policy %q anonymized %d application(s)", p.Name, n)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// retentionPolicies godoc
+// @Summary Manage retention policies
+// @Description Users with privacy:manage: list, create, update or delete retention policies. A policy anonymizes applications in one of its statuses once months have passed since basis (campaign_close or submission; applications without a closing campaign count from submission).
+// @Tags Privacy
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param id query int false "Policy ID (DELETE)"
+// @Param body body object{id=int,name=string,statuses=[]string,basis=string,months=int,active=bool} false "Policy payload (POST/PUT)"
+// @Success 200 {array} RetentionPolicy
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /retention/policies [get]
+// @Router /retention/policies [post]
+// @Router /retention/policies [put]
+// @Router /retention/policies [delete]
+func retentionPolicies(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		policies, err := loadRetentionPolicies("")
+		if err != nil {
+			log.Printf("Error fetching retention policies: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, policies, http.StatusOK)
+
+	case http.MethodPost, http.MethodPut:
+		var body RetentionPolicy
+		body.Active = true
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		body.Name = strings.TrimSpace(body.Name)
+		if body.Name == "" || len(body.Statuses) == 0 || body.Months < 1 || (r.Method == http.MethodPut && body.ID == 0) {
+			respondError(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+		if !validRetentionBasis(body.Basis) {
+			respondError(w, "Basis must be campaign_close or submission", http.StatusBadRequest)
+			return
+		}
+		for _, s := range body.Statuses {
+			if !validStatus(s) {
+				respondError(w, "Invalid status "+s, http.StatusBadRequest)
+				return
+			}
+		}
+
+		var err error
+		if r.Method == http.MethodPost {
+			err = db.QueryRow(`
+				INSERT INTO retention_policies (name, statuses, basis, months, active)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id
+			`, body.Name, pq.Array(body.Statuses), body.Basis, body.Months, body.Active).Scan(&body.ID)
+		} else {
+			var res sql.Result
+			res, err = db.Exec(`
+				UPDATE retention_policies SET name=$1, statuses=$2, basis=$3, months=$4, active=$5 WHERE id=$6
+			`, body.Name, pq.Array(body.Statuses), body.Basis, body.Months, body.Active, body.ID)
+			if err == nil {
+				if n, _ := res.RowsAffected(); n == 0 {
+					respondError(w, "Policy not found", http.StatusNotFound)
+					return
+				}
+			}
+		}
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				respondError(w, "Policy already exists", http.StatusConflict)
+				return
+			}
+			log.Printf("Error saving retention policy: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		code := http.StatusOK
+		if r.Method == http.MethodPost {
+			code = http.StatusCreated
+		}
+		respondJSON(w, map[string]interface{}{"success": true, "id": body.ID}, code)
+
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			respondError(w, "Invalid id", http.StatusBadRequest)
+			return
+		}
+		res, err := db.Exec(`DELETE FROM retention_policies WHERE id=$1`, id)
+		if err != nil {
+			log.Printf("Error deleting retention policy: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(w, "Policy not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// selectedPolicies returns the policy named by the policy_id parameter,
+// active or not, or else every active policy.
+func selectedPolicies(r *http.Request) ([]RetentionPolicy, bool, error) {
+	v := r.URL.Query().Get("policy_id")
+	if v == "" {
+		policies, err := loadRetentionPolicies(` WHERE active`)
+		return policies, true, err
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, false, nil
+	}
+	policies, err := loadRetentionPolicies(` WHERE id=$1`, id)
+	return policies, len(policies) > 0, err
+}
+
+// retentionReport godoc
+// @Summary Retention dry run
+// @Description Users with privacy:manage: the applications each active policy, or the given one, would anonymize now. Nothing is changed.
+// @Tags Privacy
+// @Produce json
+// @Security SessionAuth
+// @Param policy_id query int false "Policy ID"
+// @Success 200 {array} RetentionReport
+// @Failure 404 {string} string
+// @Router /retention/report [get]
+func retentionReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	policies, found, err := selectedPolicies(r)
+	if err != nil {
+		log.Printf("Error fetching retention policies: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		respondError(w, "Policy not found", http.StatusNotFound)
+		return
+	}
+
+	reports := []RetentionReport{}
+	for _, p := range policies {
+		due, err := dueApplications(p)
+		if err != nil {
+			log.Printf("Error computing retention report: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		reports = append(reports, RetentionReport{Policy: p, Count: len(due), Applications: due})
+	}
+	respondJSON(w, reports, http.StatusOK)
+}
+
+// runRetentionNow godoc
+// @Summary Apply retention policies
+// @Description Users with privacy:manage: anonymize now what each active policy, or the given one, covers, instead of waiting for the scheduled run
+// @Tags Privacy
+// @Produce json
+// @Security SessionAuth
+// @Param policy_id query int false "Policy ID"
+// @Success 200 {object} map[string]int
+// @Failure 404 {string} string
+// @Router /retention/run [post]
+func runRetentionNow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	policies, found, err := selectedPolicies(r)
+	if err != nil {
+		log.Printf("Error fetching retention policies: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		respondError(w, "Policy not found", http.StatusNotFound)
+		return
+	}
+
+	var actorID *int
+	if id, ok := currentUserID(r); ok {
+		actorID = &id
+	}
+	purged := map[string]int{}
+	for _, p := range policies {
+		n, err := applyRetention(p, actorID)
+		if err != nil {
+			log.Printf("Error applying retention policy %q: %v", p.Name, err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		purged[p.Name] = n
+	}
+	respondJSON(w, purged, http.StatusOK)
+}
+
+// purgeLog godoc
+// @Summary Purge log
+// @Description Users with privacy:manage: every anonymized or erased application, newest first
+// @Tags Privacy
+// @Produce json
+// @Security SessionAuth
+// @Param policy_id query int false "Policy ID"
+// @Param reason query string false "retention or erasure"
+// @Param from query string false "Purged on or after (YYYY-MM-DD)"
+// @Param to query string false "Purged on or before (YYYY-MM-DD)"
+// @Success 200 {array} PurgeEntry
+// @Failure 400 {string} string
+// @Router /retention/log [get]
+func purgeLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if v := q.Get("policy_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, "Invalid policy_id", http.StatusBadRequest)
+			return
+		}
+		add("l.policy_id = ?", id)
+	}
+	if v := q.Get("reason"); v != "" {
+		add("l.reason = ?", v)
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondError(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+		add("l.purged_at >= ?", t)
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondError(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+		add("l.purged_at < ?", t.AddDate(0, 0, 1))
+	}
+
+	query := `
+		SELECT l.id, l.application_id, l.candidate_id, l.policy_id, COALESCE(p.name, ''),
+		l.reason, l.documents, COALESCE(u.username, ''), l.purged_at
+		FROM purge_log l
+		LEFT JOIN retention_policies p ON p.id = l.policy_id
+		LEFT JOIN users u ON u.id = l.actor_id`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	rows, err := db.Query(query+` ORDER BY l.id DESC LIMIT 1000`, args...)
+	if err != nil {
+		log.Printf("Error fetching purge log: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []PurgeEntry{}
+	for rows.Next() {
+		var e PurgeEntry
+		var candidateID, policyID sql.NullInt64
+		var purged time.Time
+		if err := rows.Scan(&e.ID, &e.ApplicationID, &candidateID, &policyID, &e.Policy,
+			&e.Reason, &e.Documents, &e.Actor, &purged); err != nil {
+			log.Printf("Error scanning purge entry: %v", err)
+			continue
+		}
+		if candidateID.Valid {
+			id := int(candidateID.Int64)
+			e.CandidateID = &id
+		}
+		if policyID.Valid {
+			id := int(policyID.Int64)
+			e.PolicyID = &id
+		}
+		e.PurgedAt = purged.Format(time.RFC3339)
+		entries = append(entries, e)
+	}
+	respondJSON(w, entries, http.StatusOK)
+}
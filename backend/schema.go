@@ -0,0 +1,516 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// schemaStatements brings the database to the shape the handlers expect.
+// Every statement is idempotent, so the whole list is replayed on startup
+// and new features simply append to it.
+var schemaStatements = []string{
+	// Base tables
+	`CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		email TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS subjects (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE
+	)`,
+	`CREATE TABLE IF NOT EXISTS applications (
+		id SERIAL PRIMARY KEY,
+		full_name TEXT NOT NULL DEFAULT '',
+		gender TEXT NOT NULL DEFAULT '',
+		email TEXT NOT NULL,
+		phone TEXT NOT NULL DEFAULT '',
+		university TEXT NOT NULL DEFAULT '',
+		field_of_study TEXT NOT NULL DEFAULT '',
+		degree_level TEXT NOT NULL DEFAULT '',
+		application_type TEXT NOT NULL DEFAULT '',
+		internship_duration TEXT NOT NULL DEFAULT '',
+		preferred_working_method TEXT NOT NULL DEFAULT '',
+		start_date DATE,
+		cv_file_path TEXT NOT NULL,
+		motivation_file_path TEXT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS application_subjects (
+		application_id INT NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+		subject_id INT NOT NULL REFERENCES subjects(id),
+		PRIMARY KEY (application_id, subject_id)
+	)`,
+
+	// Campaigns, candidates and application status
+	`CREATE TABLE IF NOT EXISTS campaigns (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		opens_on DATE NOT NULL,
+		closes_on DATE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS candidates (
+		id SERIAL PRIMARY KEY,
+		email TEXT NOT NULL UNIQUE,
+		full_name TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`ALTER TABLE applications DROP CONSTRAINT IF EXISTS applications_email_key`,
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS candidate_id INT REFERENCES candidates(id)`,
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS campaign_id INT REFERENCES campaigns(id)`,
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'submitted'`,
+	`INSERT INTO candidates (email, full_name, created_at)
+		SELECT DISTINCT ON (LOWER(TRIM(email))) LOWER(TRIM(email)), full_name, created_at
+		FROM applications
+		WHERE candidate_id IS NULL
+		ORDER BY LOWER(TRIM(email)), created_at DESC
+		ON CONFLICT (email) DO NOTHING`,
+	`UPDATE applications a SET candidate_id = c.id
+		FROM candidates c
+		WHERE a.candidate_id IS NULL AND c.email = LOWER(TRIM(a.email))`,
+	`ALTER TABLE applications ALTER COLUMN candidate_id SET NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS applications_candidate_idx ON applications (candidate_id)`,
+	applicationsOpenPerCampaignIndex,
+
+	// Applicant portal: single-use login links, and sessions so they can be
+	// ended on the server
+	`CREATE TABLE IF NOT EXISTS magic_links (
+		id SERIAL PRIMARY KEY,
+		candidate_id INT NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS magic_links_candidate_idx ON magic_links (candidate_id)`,
+	`CREATE TABLE IF NOT EXISTS applicant_sessions (
+		id SERIAL PRIMARY KEY,
+		candidate_id INT NOT NULL REFERENCES candidates(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		revoked_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS applicant_sessions_candidate_idx ON applicant_sessions (candidate_id)`,
+
+	// Email outbox
+	`CREATE TABLE IF NOT EXISTS email_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		recipient TEXT NOT NULL,
+		template TEXT NOT NULL,
+		payload JSONB NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		sent_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS email_outbox_due_idx
+		ON email_outbox (next_attempt_at) WHERE status = 'pending'`,
+	`CREATE INDEX IF NOT EXISTS email_outbox_template_idx ON email_outbox (template, created_at)`,
+
+	// Webhooks
+	`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		subscription_id INT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		response_code INT,
+		last_error TEXT,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		delivered_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+		ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx
+		ON webhook_deliveries (subscription_id, id)`,
+	`CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+		id BIGSERIAL PRIMARY KEY,
+		delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+		response_code INT,
+		response_body TEXT NOT NULL DEFAULT '',
+		error TEXT,
+		duration_ms BIGINT NOT NULL,
+		attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+
+	// Interviews
+	`CREATE TABLE IF NOT EXISTS interview_slots (
+		id SERIAL PRIMARY KEY,
+		interviewer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		starts_at TIMESTAMPTZ NOT NULL,
+		ends_at TIMESTAMPTZ NOT NULL,
+		location TEXT NOT NULL DEFAULT '',
+		meeting_url TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CHECK (ends_at > starts_at)
+	)`,
+	`CREATE INDEX IF NOT EXISTS interview_slots_interviewer_idx
+		ON interview_slots (interviewer_id, starts_at)`,
+	`CREATE TABLE IF NOT EXISTS interviews (
+		id SERIAL PRIMARY KEY,
+		slot_id INT NOT NULL REFERENCES interview_slots(id) ON DELETE CASCADE,
+		application_id INT NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+		status TEXT NOT NULL DEFAULT 'scheduled',
+		booked_by TEXT NOT NULL,
+		sequence INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS interviews_slot_booked
+		ON interviews (slot_id) WHERE status = 'scheduled'`,
+	`CREATE INDEX IF NOT EXISTS interviews_application_idx ON interviews (application_id)`,
+
+	// Calendar feeds are read through a revocable secret link
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_feed_hash TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_calendar_feed_idx ON users (calendar_feed_hash)`,
+
+	// Scoring rubrics and scorecards
+	`CREATE TABLE IF NOT EXISTS rubrics (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		hide_scores BOOLEAN NOT NULL DEFAULT FALSE,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS rubrics_single_default ON rubrics (is_default) WHERE is_default`,
+	`CREATE TABLE IF NOT EXISTS rubric_criteria (
+		id SERIAL PRIMARY KEY,
+		rubric_id INT NOT NULL REFERENCES rubrics(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		weight NUMERIC NOT NULL CHECK (weight > 0),
+		scale_min INT NOT NULL DEFAULT 1,
+		scale_max INT NOT NULL DEFAULT 5,
+		position INT NOT NULL DEFAULT 0,
+		CHECK (scale_max > scale_min)
+	)`,
+	`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS rubric_id INT REFERENCES rubrics(id)`,
+	`CREATE TABLE IF NOT EXISTS scorecards (
+		id SERIAL PRIMARY KEY,
+		application_id INT NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+		reviewer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		rubric_id INT NOT NULL REFERENCES rubrics(id),
+		status TEXT NOT NULL DEFAULT 'draft',
+		comment TEXT NOT NULL DEFAULT '',
+		total NUMERIC,
+		submitted_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (application_id, reviewer_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS scorecard_scores (
+		scorecard_id INT NOT NULL REFERENCES scorecards(id) ON DELETE CASCADE,
+		criterion_id INT NOT NULL REFERENCES rubric_criteria(id),
+		score INT NOT NULL,
+		PRIMARY KEY (scorecard_id, criterion_id)
+	)`,
+
+	// Comments
+	`CREATE TABLE IF NOT EXISTS comments (
+		id SERIAL PRIMARY KEY,
+		application_id INT NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+		parent_id INT REFERENCES comments(id) ON DELETE CASCADE,
+		author_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		visibility TEXT NOT NULL DEFAULT 'team',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		deleted_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS comments_application_idx ON comments (application_id, created_at)`,
+	`CREATE TABLE IF NOT EXISTS comment_revisions (
+		id SERIAL PRIMARY KEY,
+		comment_id INT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS comment_mentions (
+		comment_id INT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		PRIMARY KEY (comment_id, user_id)
+	)`,
+
+	// Reviewer assignment
+	`CREATE TABLE IF NOT EXISTS reviewer_pool (
+		user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		last_assigned_at TIMESTAMPTZ
+	)`,
+	`CREATE TABLE IF NOT EXISTS subject_owners (
+		subject_id INT NOT NULL REFERENCES subjects(id) ON DELETE CASCADE,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		PRIMARY KEY (subject_id, user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS application_assignments (
+		application_id INT NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+		reviewer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		method TEXT NOT NULL,
+		assigned_by INT REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (application_id, reviewer_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS application_assignments_reviewer_idx
+		ON application_assignments (reviewer_id)`,
+	`CREATE TABLE IF NOT EXISTS assignment_history (
+		id BIGSERIAL PRIMARY KEY,
+		application_id INT NOT NULL REFERENCES applications(id) ON DELETE CASCADE,
+		reviewer_id INT REFERENCES users(id) ON DELETE SET NULL,
+		action TEXT NOT NULL,
+		method TEXT NOT NULL,
+		actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS assignment_history_application_idx
+		ON assignment_history (application_id, id)`,
+
+	// Audit log. Rows are chained by hash and the triggers refuse any change,
+	// so actor_id deliberately has no foreign key to cascade from.
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id INT,
+		actor TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		entity_type TEXT NOT NULL DEFAULT '',
+		entity_id TEXT NOT NULL DEFAULT '',
+		before TEXT,
+		after TEXT,
+		status INT NOT NULL,
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL,
+		prev_hash TEXT NOT NULL,
+		hash TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor_id, id)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id, id)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_created_idx ON audit_log (created_at)`,
+	`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log`,
+	`CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+		FOR EACH ROW EXECUTE FUNCTION audit_log_append_only()`,
+	`DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log`,
+	`CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()`,
+
+	// Data retention
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ`,
+	`CREATE TABLE IF NOT EXISTS retention_policies (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		statuses TEXT[] NOT NULL,
+		basis TEXT NOT NULL,
+		months INT NOT NULL CHECK (months > 0),
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS purge_log (
+		id BIGSERIAL PRIMARY KEY,
+		application_id INT NOT NULL,
+		candidate_id INT,
+		policy_id INT REFERENCES retention_policies(id) ON DELETE SET NULL,
+		reason TEXT NOT NULL,
+		documents INT NOT NULL DEFAULT 0,
+		actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+		purged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS purge_log_purged_idx ON purge_log (purged_at)`,
+
+	// Privacy notices and consent
+	`CREATE TABLE IF NOT EXISTS privacy_notices (
+		version SERIAL PRIMARY KEY,
+		body TEXT NOT NULL,
+		published_by INT REFERENCES users(id) ON DELETE SET NULL,
+		published_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`INSERT INTO privacy_notices (body)
+		SELECT 'Your application, including the documents you upload, is processed to assess it for an internship. '
+			|| 'It is only shared with the people involved in recruitment and kept for as long as our retention policy allows. '
+			|| 'You can request a copy of your data or its erasure at any time from the applicant portal.'
+		WHERE NOT EXISTS (SELECT 1 FROM privacy_notices)`,
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS consent_version INT REFERENCES privacy_notices(version)`,
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS consented_at TIMESTAMPTZ`,
+	`ALTER TABLE applications ADD COLUMN IF NOT EXISTS keep_profile BOOLEAN NOT NULL DEFAULT FALSE`,
+
+	// Password reset
+	`CREATE TABLE IF NOT EXISTS password_resets (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS password_resets_user_idx ON password_resets (user_id)`,
+
+	// Two-factor authentication
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS recovery_codes_user_idx ON recovery_codes (user_id)`,
+	`CREATE TABLE IF NOT EXISTS mfa_required_roles (
+		role TEXT PRIMARY KEY
+	)`,
+
+	// Logins waiting for their second factor. The failed attempts are
+	// counted here, out of reach of the client.
+	`CREATE TABLE IF NOT EXISTS two_factor_logins (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		attempts INT NOT NULL DEFAULT 0,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS two_factor_logins_user_idx ON two_factor_logins (user_id)`,
+
+	// Single sign-on
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer TEXT`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_idx ON users (oidc_issuer, oidc_subject)`,
+
+	// API tokens
+	`CREATE TABLE IF NOT EXISTS api_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT[] NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		last_used_at TIMESTAMPTZ,
+		last_used_ip TEXT,
+		revoked_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS api_tokens_active_name_idx ON api_tokens (user_id, name) WHERE revoked_at IS NULL`,
+
+	// Email verification. Accounts that exist when the column is added count
+	// as verified; the default is dropped right after, so new ones start
+	// unverified.
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ DEFAULT NOW()`,
+	`ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMPTZ`,
+
+	// Server-side sessions, which replace the session version of users
+	`CREATE TABLE IF NOT EXISTS user_sessions (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		ip TEXT,
+		user_agent TEXT,
+		two_factor BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		revoked_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS user_sessions_user_idx ON user_sessions (user_id)`,
+	`ALTER TABLE users DROP COLUMN IF EXISTS session_version`,
+}
+
+// applicationsOpenPerCampaignIndex allows one open application per candidate
+// and campaign
+const applicationsOpenPerCampaignIndex = `CREATE UNIQUE INDEX IF NOT EXISTS applications_open_per_campaign
+	ON applications (candidate_id, COALESCE(campaign_id, 0))
+	WHERE status NOT IN ('rejected', 'withdrawn')`
+
+// schemaChecks run before the statement they are keyed by while it has not
+// been applied yet, and stop the migration when existing rows would make it
+// fail. They only report those rows: deciding what to do with them is left
+// to an admin.
+var schemaChecks = map[string]func() error{
+	applicationsOpenPerCampaignIndex: checkDuplicateApplications,
+}
+
+// checkDuplicateApplications lists the candidates with several open
+// applications in one campaign. Before candidates, emails that only differed
+// in case or spaces were separate applicants, so one can now have several.
+func checkDuplicateApplications() error {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('applications_open_per_campaign') IS NOT NULL`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	rows, err := db.Query(`
+		SELECT candidate_id, campaign_id, ARRAY_AGG(id ORDER BY id)
+		FROM applications
+		WHERE status NOT IN ('rejected', 'withdrawn')
+		GROUP BY candidate_id, campaign_id
+		HAVING COUNT(*) > 1
+		ORDER BY candidate_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	conflicts := 0
+	for rows.Next() {
+		var candidateID int
+		var campaignID sql.NullInt64
+		var ids []int64
+		if err := rows.Scan(&candidateID, &campaignID, pq.Array(&ids)); err != nil {
+			return err
+		}
+		campaign := "no campaign"
+		if campaignID.Valid {
+			campaign = fmt.Sprintf("campaign %d", campaignID.Int64)
+		}
+		log.Printf("Candidate %d has several open applications in %s: %v", candidateID, campaign, ids)
+		conflicts++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d candidate(s) have several open applications in one campaign; withdraw or reject all but one of each, then restart", conflicts)
+	}
+	return nil
+}
+
+func migrate() error {
+	for i, stmt := range schemaStatements {
+		if check, ok := schemaChecks[stmt]; ok {
+			if err := check(); err != nil {
+				return fmt.Errorf("schema statement %d: %w", i, err)
+			}
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("schema statement %d: %w", i, err)
+		}
+	}
+	log.Printf("Database schema up to date (%d statements)", len(schemaStatements))
+	return nil
+}
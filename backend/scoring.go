@@ -0,0 +1,719 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Scorecard statuses
+const (
+	ScorecardDraft     = "draft"
+	ScorecardSubmitted = "submitted"
+)
+
+var (
+	errNoRubric            = errors.New("No rubric configured for this application")
+	errIncompleteScorecard = errors.New("Every criterion must be scored before submitting")
+)
+
+// scoresHidden holds over the submitted scorecards "c" of one application,
+// joined to their rubrics "r", when they are hidden from the user bound to
+// "?": the rubric hides scores and that user is assigned to the application
+// but has not submitted theirs yet. Users who only decide see them.
+const scoresHidden = `BOOL_OR(r.hide_scores) AND NOT BOOL_OR(c.reviewer_id = ?)
+	AND EXISTS (
+		SELECT 1 FROM application_assignments aa
+		WHERE aa.application_id = c.application_id AND aa.reviewer_id = ?
+	)`
+
+// applicationScoreExpr is the aggregated score of an application aliased "a"
+// as seen by the user bound to "?": NULL where attachScores hides it from
+// them, so that sorting by score does not give hidden scores away.
+const applicationScoreExpr = `(SELECT CASE WHEN ` + scoresHidden + ` THEN NULL ELSE AVG(c.total) END
+	FROM scorecards c JOIN rubrics r ON r.id = c.rubric_id
+	WHERE c.application_id = a.id AND c.status = 'submitted'
+	GROUP BY c.application_id)`
+
+// RubricCriterion is one weighted, scaled dimension of a rubric
+type RubricCriterion struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Weight      float64 `json:"weight"`
+	ScaleMin    int     `json:"scale_min"`
+	ScaleMax    int     `json:"scale_max"`
+}
+
+// Rubric is a set of criteria reviewers rate applications against
+type Rubric struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	HideScores  bool              `json:"hide_scores"`
+	IsDefault   bool              `json:"is_default"`
+	InUse       bool              `json:"in_use"`
+	Criteria    []RubricCriterion `json:"criteria"`
+}
+
+// CriterionScore is the rating given for one criterion
+type CriterionScore struct {
+	CriterionID int `json:"criterion_id"`
+	Score       int `json:"score"`
+}
+
+// Scorecard is one reviewer's evaluation of an application
+type Scorecard struct {
+	ID          int              `json:"id"`
+	ReviewerID  int              `json:"reviewer_id"`
+	Reviewer    string           `json:"reviewer"`
+	Status      string           `json:"status"`
+	Comment     string           `json:"comment,omitempty"`
+	Total       *float64         `json:"total,omitempty"`
+	Scores      []CriterionScore `json:"scores"`
+	SubmittedAt *string          `json:"submitted_at,omitempty"`
+	UpdatedAt   string           `json:"updated_at"`
+}
+
+// CriterionAggregate summarises the submitted ratings of one criterion
+type CriterionAggregate struct {
+	CriterionID int     `json:"criterion_id"`
+	Mean        float64 `json:"mean"`
+	Variance    float64 `json:"variance"`
+}
+
+// ScoreAggregate summarises the submitted scorecards of an application.
+// Totals are weighted and normalised to 0-100.
+type ScoreAggregate struct {
+	Count    int                  `json:"count"`
+	Mean     *float64             `json:"mean,omitempty"`
+	Variance *float64             `json:"variance,omitempty"`
+	StdDev   *float64             `json:"std_dev,omitempty"`
+	Criteria []CriterionAggregate `json:"criteria"`
+}
+
+// ScoreSheet is the evaluation of an application as seen by one reviewer.
+// With a hiding rubric, other scorecards and the aggregate are left out
+// until the reviewer has submitted their own.
+type ScoreSheet struct {
+	ApplicationID int             `json:"application_id"`
+	Rubric        Rubric          `json:"rubric"`
+	Mine          *Scorecard      `json:"mine,omitempty"`
+	Others        []Scorecard     `json:"others,omitempty"`
+	Aggregate     *ScoreAggregate `json:"aggregate,omitempty"`
+	Hidden        bool            `json:"hidden"`
+	Submitted     int             `json:"submitted"`
+}
+
+// loadRubrics fetches rubrics with their criteria in display order
+func loadRubrics(where string, args ...interface{}) ([]Rubric, error) {
+	query := `SELECT r.id, r.name, r.description, r.hide_scores, r.is_default,
+		EXISTS (SELECT 1 FROM scorecards c WHERE c.rubric_id = r.id)
+		FROM rubrics r`
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := db.Query(query+" ORDER BY r.name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rubrics := []Rubric{}
+	var ids []int
+	for rows.Next() {
+		rb := Rubric{Criteria: []RubricCriterion{}}
+		if err := rows.Scan(&rb.ID, &rb.Name, &rb.Description, &rb.HideScores, &rb.IsDefault, &rb.InUse); err != nil {
+			return nil, err
+		}
+		rubrics = append(rubrics, rb)
+		ids = append(ids, rb.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return rubrics, nil
+	}
+
+	crit, err := db.Query(`
+		SELECT rubric_id, id, name, description, weight, scale_min, scale_max
+		FROM rubric_criteria WHERE rubric_id = ANY($1)
+		ORDER BY position, id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer crit.Close()
+
+	index := map[int]int{}
+	for i, rb := range rubrics {
+		index[rb.ID] = i
+	}
+	for crit.Next() {
+		var rubricID int
+		var c RubricCriterion
+		if err := crit.Scan(&rubricID, &c.ID, &c.Name, &c.Description, &c.Weight, &c.ScaleMin, &c.ScaleMax); err != nil {
+			return nil, err
+		}
+		rb := &rubrics[index[rubricID]]
+		rb.Criteria = append(rb.Criteria, c)
+	}
+	return rubrics, crit.Err()
+}
+
+// applicationRubric returns the rubric an application is scored with: the one
+// of its existing scorecards, else its campaign's, else the default rubric.
+func applicationRubric(appID int) (Rubric, error) {
+	var rubricID sql.NullInt64
+	err := db.QueryRow(`
+		SELECT COALESCE(
+			(SELECT c.rubric_id FROM scorecards c WHERE c.application_id = a.id ORDER BY c.id LIMIT 1),
+			cp.rubric_id,
+			(SELECT r.id FROM rubrics r WHERE r.is_default)
+		)
+		FROM applications a
+		LEFT JOIN campaigns cp ON cp.id = a.campaign_id
+		WHERE a.id=$1`, appID).Scan(&rubricID)
+	if err != nil {
+		return Rubric{}, err
+	}
+	if !rubricID.Valid {
+		return Rubric{}, errNoRubric
+	}
+
+	rubrics, err := loadRubrics("r.id=$1", rubricID.Int64)
+	if err != nil {
+		return Rubric{}, err
+	}
+	if len(rubrics) == 0 {
+		return Rubric{}, errNoRubric
+	}
+	return rubrics[0], nil
+}
+
+// weightedTotal normalises every score to its scale and returns the weighted
+// mean on 0-100, or nil unless every criterion is scored.
+func weightedTotal(rb Rubric, scores map[int]int) *float64 {
+	var sum, weights float64
+	for _, c := range rb.Criteria {
+		score, ok := scores[c.ID]
+		if !ok {
+			return nil
+		}
+		sum += c.Weight * float64(score-c.ScaleMin) / float64(c.ScaleMax-c.ScaleMin)
+		weights += c.Weight
+	}
+	if weights == 0 {
+		return nil
+	}
+	total := math.Round(sum/weights*10000) / 100
+	return &total
+}
+
+// loadScorecards fetches the scorecards of an application with their scores
+func loadScorecards(appID int) ([]Scorecard, error) {
+	rows, err := db.Query(`
+		SELECT c.id, c.reviewer_id, u.username, c.status, c.comment, c.total,
+		c.submitted_at, c.updated_at,
+		COALESCE(ARRAY_AGG(s.criterion_id ORDER BY s.criterion_id) FILTER (WHERE s.criterion_id IS NOT NULL), '{}'),
+		COALESCE(ARRAY_AGG(s.score ORDER BY s.criterion_id) FILTER (WHERE s.criterion_id IS NOT NULL), '{}')
+		FROM scorecards c
+		JOIN users u ON u.id = c.reviewer_id
+		LEFT JOIN scorecard_scores s ON s.scorecard_id = c.id
+		WHERE c.application_id=$1
+		GROUP BY c.id, u.username
+		ORDER BY c.submitted_at NULLS LAST, c.id
+	`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []Scorecard
+	for rows.Next() {
+		var c Scorecard
+		var total sql.NullFloat64
+		var submitted sql.NullTime
+		var updated time.Time
+		var criteria, scores []int64
+		if err := rows.Scan(&c.ID, &c.ReviewerID, &c.Reviewer, &c.Status, &c.Comment, &total,
+			&submitted, &updated, pq.Array(&criteria), pq.Array(&scores)); err != nil {
+			return nil, err
+		}
+		if total.Valid {
+			c.Total = &total.Float64
+		}
+		if submitted.Valid {
+			s := submitted.Time.Format(time.RFC3339)
+			c.SubmittedAt = &s
+		}
+		c.UpdatedAt = updated.Format(time.RFC3339)
+		c.Scores = make([]CriterionScore, len(criteria))
+		for i := range criteria {
+			c.Scores[i] = CriterionScore{CriterionID: int(criteria[i]), Score: int(scores[i])}
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+func scoreAggregate(appID int) (*ScoreAggregate, error) {
+	agg := &ScoreAggregate{Criteria: []CriterionAggregate{}}
+	var mean, variance sql.NullFloat64
+	if err := db.QueryRow(`
+		SELECT COUNT(*), AVG(total), VAR_POP(total)
+		FROM scorecards WHERE application_id=$1 AND status='submitted'
+	`, appID).Scan(&agg.Count, &mean, &variance); err != nil {
+		return nil, err
+	}
+	if mean.Valid {
+		agg.Mean = &mean.Float64
+		agg.Variance = &variance.Float64
+		sd := math.Sqrt(variance.Float64)
+		agg.StdDev = &sd
+	}
+
+	rows, err := db.Query(`
+		SELECT s.criterion_id, AVG(s.score), VAR_POP(s.score)
+		FROM scorecard_scores s
+		JOIN scorecards c ON c.id = s.scorecard_id
+		WHERE c.application_id=$1 AND c.status='submitted'
+		GROUP BY s.criterion_id
+		ORDER BY s.criterion_id
+	`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c CriterionAggregate
+		if err := rows.Scan(&c.CriterionID, &c.Mean, &c.Variance); err != nil {
+			return nil, err
+		}
+		agg.Criteria = append(agg.Criteria, c)
+	}
+	return agg, rows.Err()
+}
+
+// attachScores sets the aggregated score on listed applications, leaving it
+// out where scoresHidden hides it from the viewer.
+func attachScores(apps []ApplicationResponse, viewerID int) error {
+	if len(apps) == 0 {
+		return nil
+	}
+	ids := make([]int, len(apps))
+	for i, a := range apps {
+		ids[i] = a.ID
+	}
+
+	rows, err := db.Query(`
+		SELECT c.application_id, COUNT(*), AVG(c.total), VAR_POP(c.total),
+		`+strings.ReplaceAll(scoresHidden, "?", "$2")+`
+		FROM scorecards c
+		JOIN rubrics r ON r.id = c.rubric_id
+		WHERE c.application_id = ANY($1) AND c.status = 'submitted'
+		GROUP BY c.application_id
+	`, pq.Array(ids), viewerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := map[int]int{}
+	for i, a := range apps {
+		index[a.ID] = i
+	}
+	for rows.Next() {
+		var appID, count int
+		var mean, variance float64
+		var hidden bool
+		if err := rows.Scan(&appID, &count, &mean, &variance, &hidden); err != nil {
+			return err
+		}
+		a := &apps[index[appID]]
+		a.Scorecards = count
+		if !hidden {
+			a.Score, a.ScoreVariance = &mean, &variance
+		}
+	}
+	return rows.Err()
+}
+
+// validateCriteria checks a rubric definition from a request body
+func validateCriteria(criteria []RubricCriterion) string {
+	if len(criteria) == 0 {
+		return "A rubric needs at least one criterion"
+	}
+	for i := range criteria {
+		c := &criteria[i]
+		c.Name = strings.TrimSpace(c.Name)
+		if c.ScaleMin == 0 && c.ScaleMax == 0 {
+			c.ScaleMin, c.ScaleMax = 1, 5
+		}
+		if c.Name == "" || c.Weight <= 0 || c.ScaleMax <= c.ScaleMin {
+			return "Each criterion needs a name, a positive weight and scale_max above scale_min"
+		}
+	}
+	return ""
+}
+
+// rubricsHandler godoc
+// @Summary Manage scoring rubrics
+// @Description Admin: list rubrics (GET), create one (POST), update one (PUT) or delete an unused one (DELETE). Criteria of a rubric that has scorecards cannot be changed; create a new rubric instead. hide_scores is off unless given.
+// @Tags Scoring
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int,name=string,description=string,hide_scores=bool,is_default=bool,criteria=[]RubricCriterion} false "Rubric payload (POST/PUT), {id} (DELETE)"
+// @Success 200 {array} Rubric
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {string} string
+// @Failure 409 {string} string
+// @Router /rubrics [get]
+// @Router /rubrics [post]
+// @Router /rubrics [put]
+// @Router /rubrics [delete]
+func rubricsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rubrics, err := loadRubrics("")
+		if err != nil {
+			log.Printf("Error fetching rubrics: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, rubrics, http.StatusOK)
+
+	case http.MethodPost, http.MethodPut:
+		var body struct {
+			ID          int               `json:"id"`
+			Name        string            `json:"name"`
+			Description string            `json:"description"`
+			HideScores  *bool             `json:"hide_scores"`
+			IsDefault   bool              `json:"is_default"`
+			Criteria    []RubricCriterion `json:"criteria"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		body.Name = strings.TrimSpace(body.Name)
+		if body.Name == "" || (r.Method == http.MethodPut && body.ID == 0) {
+			respondError(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPost || body.Criteria != nil {
+			if msg := validateCriteria(body.Criteria); msg != "" {
+				respondError(w, msg, http.StatusBadRequest)
+				return
+			}
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		if body.IsDefault {
+			if _, err := tx.Exec(`UPDATE rubrics SET is_default=FALSE WHERE is_default AND id<>$1`, body.ID); err != nil {
+				log.Printf("Error clearing default rubric: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if r.Method == http.MethodPost {
+			err = tx.QueryRow(`
+				INSERT INTO rubrics (name, description, hide_scores, is_default)
+				VALUES ($1, $2, COALESCE($3, FALSE), $4) RETURNING id
+			`, body.Name, body.Description, body.HideScores, body.IsDefault).Scan(&body.ID)
+		} else {
+			var inUse bool
+			err = tx.QueryRow(`
+				UPDATE rubrics SET name=$1, description=$2, hide_scores=COALESCE($3, hide_scores), is_default=$4
+				WHERE id=$5
+				RETURNING EXISTS (SELECT 1 FROM scorecards c WHERE c.rubric_id = rubrics.id)
+			`, body.Name, body.Description, body.HideScores, body.IsDefault, body.ID).Scan(&inUse)
+			if err == sql.ErrNoRows {
+				respondError(w, "Rubric not found", http.StatusNotFound)
+				return
+			}
+			if err == nil && body.Criteria != nil {
+				if inUse {
+					respondError(w, "Rubric already has scorecards; create a new rubric to change its criteria", http.StatusConflict)
+					return
+				}
+				_, err = tx.Exec(`DELETE FROM rubric_criteria WHERE rubric_id=$1`, body.ID)
+			}
+		}
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				respondError(w, "Rubric already exists", http.StatusConflict)
+				return
+			}
+			log.Printf("Error saving rubric: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		for i, c := range body.Criteria {
+			if _, err := tx.Exec(`
+				INSERT INTO rubric_criteria (rubric_id, name, description, weight, scale_min, scale_max, position)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, body.ID, c.Name, c.Description, c.Weight, c.ScaleMin, c.ScaleMax, i); err != nil {
+				log.Printf("Error saving rubric criterion: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing rubric: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		code := http.StatusOK
+		if r.Method == http.MethodPost {
+			code = http.StatusCreated
+		}
+		respondJSON(w, map[string]interface{}{"success": true, "id": body.ID}, code)
+
+	case http.MethodDelete:
+		var body struct {
+			ID int `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		result, err := db.Exec(`DELETE FROM rubrics WHERE id=$1`, body.ID)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				respondError(w, "Rubric is used by scorecards or campaigns", http.StatusConflict)
+				return
+			}
+			log.Printf("Error deleting rubric: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			respondError(w, "Rubric not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// scorecardsHandler godoc
+// @Summary Application scorecards
+// @Description Admin: the rubric, your scorecard, and the other reviewers' submitted scorecards with the aggregated score of an application (GET). Save your scorecard as a draft or submit it (PUT). When the rubric hides scores and you are assigned to the application, others' scorecards and the aggregate are only returned once yours is submitted.
+// @Tags Scoring
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param application_id query int false "Application ID (GET)"
+// @Param body body object{application_id=int,scores=[]CriterionScore,comment=string,submit=bool} false "Scorecard payload (PUT)"
+// @Success 200 {object} ScoreSheet
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /applications/scorecards [get]
+// @Router /applications/scorecards [put]
+func scorecardsHandler(w http.ResponseWriter, r *http.Request) {
+	reviewerID, ok := currentUserID(r)
+	if !ok {
+		respondError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var appID int
+	var body struct {
+		ApplicationID int              `json:"application_id"`
+		Scores        []CriterionScore `json:"scores"`
+		Comment       string           `json:"comment"`
+		Submit        bool             `json:"submit"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		id, err := strconv.Atoi(r.URL.Query().Get("application_id"))
+		if err != nil {
+			respondError(w, "Invalid application_id", http.StatusBadRequest)
+			return
+		}
+		appID = id
+	case http.MethodPut:
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		appID = body.ApplicationID
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireApplication(w, r, appID) {
+		return
+	}
+
+	rubric, err := applicationRubric(appID)
+	if err == sql.ErrNoRows {
+		respondError(w, "Application not found", http.StatusNotFound)
+		return
+	} else if err == errNoRubric {
+		respondError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching rubric: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		criteria := map[int]RubricCriterion{}
+		for _, c := range rubric.Criteria {
+			criteria[c.ID] = c
+		}
+		scores := map[int]int{}
+		for _, s := range body.Scores {
+			c, ok := criteria[s.CriterionID]
+			if !ok {
+				respondError(w, "Unknown criterion "+strconv.Itoa(s.CriterionID), http.StatusBadRequest)
+				return
+			}
+			if s.Score < c.ScaleMin || s.Score > c.ScaleMax {
+				respondError(w, "Score for "+c.Name+" must be between "+
+					strconv.Itoa(c.ScaleMin)+" and "+strconv.Itoa(c.ScaleMax), http.StatusBadRequest)
+				return
+			}
+			scores[s.CriterionID] = s.Score
+		}
+
+		if err := saveScorecard(appID, reviewerID, rubric, scores, strings.TrimSpace(body.Comment), body.Submit); err == errIncompleteScorecard {
+			respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				respondError(w, "Scorecard was saved concurrently, please retry", http.StatusConflict)
+				return
+			}
+			log.Printf("Error saving scorecard: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	cards, err := loadScorecards(appID)
+	if err != nil {
+		log.Printf("Error fetching scorecards: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	sheet := ScoreSheet{ApplicationID: appID, Rubric: rubric}
+	var others []Scorecard
+	for i := range cards {
+		if cards[i].ReviewerID == reviewerID {
+			sheet.Mine = &cards[i]
+		} else if cards[i].Status == ScorecardSubmitted {
+			others = append(others, cards[i])
+		}
+		if cards[i].Status == ScorecardSubmitted {
+			sheet.Submitted++
+		}
+	}
+
+	if rubric.HideScores && (sheet.Mine == nil || sheet.Mine.Status != ScorecardSubmitted) {
+		if err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM application_assignments WHERE application_id=$1 AND reviewer_id=$2)
+		`, appID, reviewerID).Scan(&sheet.Hidden); err != nil {
+			log.Printf("Error fetching assignment: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if !sheet.Hidden {
+		sheet.Others = others
+		if sheet.Aggregate, err = scoreAggregate(appID); err != nil {
+			log.Printf("Error aggregating scores: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	respondJSON(w, sheet, http.StatusOK)
+}
+
+// saveScorecard stores a reviewer's scores. A submitted scorecard stays
+// submitted when edited, so it must remain complete.
+func saveScorecard(appID, reviewerID int, rubric Rubric, scores map[int]int, comment string, submit bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var cardID int
+	var status string
+	err = tx.QueryRow(`
+		SELECT id, status FROM scorecards WHERE application_id=$1 AND reviewer_id=$2 FOR UPDATE
+	`, appID, reviewerID).Scan(&cardID, &status)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if submit {
+		status = ScorecardSubmitted
+	} else if status == "" {
+		status = ScorecardDraft
+	}
+
+	total := weightedTotal(rubric, scores)
+	if status == ScorecardSubmitted && total == nil {
+		return errIncompleteScorecard
+	}
+
+	if cardID == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO scorecards (application_id, reviewer_id, rubric_id, status, comment, total, submitted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $4 = 'submitted' THEN NOW() END)
+			RETURNING id
+		`, appID, reviewerID, rubric.ID, status, comment, total).Scan(&cardID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE scorecards SET status=$1, comment=$2, total=$3, updated_at=NOW(),
+			submitted_at = CASE WHEN $1 = 'submitted' THEN COALESCE(submitted_at, NOW()) END
+			WHERE id=$4
+		`, status, comment, total, cardID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM scorecard_scores WHERE scorecard_id=$1`, cardID); err != nil {
+		return err
+	}
+	for criterionID, score := range scores {
+		if _, err := tx.Exec(`
+			INSERT INTO scorecard_scores (scorecard_id, criterion_id, score) VALUES ($1, $2, $3)
+		`, cardID, criterionID, score); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Back-office sessions are tracked in user_sessions: the "auth" cookie only
+// names a row, by a random id stored hashed, and the session ends when the
+// row is revoked, after sessionIdleTimeout without requests, or
+// sessionMaxAge after sign-in, whatever the cookie says.
+const sessionMaxAge = 24 * time.Hour
+
+var sessionIdleTimeout = parseDuration(getEnv("SESSION_IDLE_TIMEOUT", "30m"))
+
+// UserSession is one signed-in browser or device of a user
+type UserSession struct {
+	ID         int    `json:"id"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	Current    bool   `json:"current"`
+}
+
+// activeSession is the condition on user_sessions rows, aliased "s", that
+// are still in use. idle and maxAge are the placeholders of the idle timeout
+// and maximum age in seconds.
+func activeSession(idle, maxAge string) string {
+	return `s.revoked_at IS NULL
+		AND s.last_seen_at > NOW() - MAKE_INTERVAL(secs => ` + idle + `)
+		AND s.created_at > NOW() - MAKE_INTERVAL(secs => ` + maxAge + `)`
+}
+
+// startSession signs userID in on this browser: it records a new session and
+// points the "auth" cookie at it. twoFactor tells whether a second factor
+// was given; it is kept on the session row, where the client cannot set it.
+func startSession(w http.ResponseWriter, r *http.Request, userID int, role, username string, twoFactor bool) error {
+	sid, err := randomString()
+	if err != nil {
+		return err
+	}
+	// Finished sessions are only kept a while, for the user to look back on
+	if _, err := db.Exec(`
+		DELETE FROM user_sessions s WHERE s.user_id=$1 AND s.last_seen_at < NOW() - INTERVAL '30 days'
+	`, userID); err != nil {
+		log.Printf("Error cleaning up sessions: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO user_sessions (user_id, token_hash, ip, user_agent, two_factor) VALUES ($1, $2, $3, $4, $5)
+	`, userID, hashToken(sid), clientIP(r), r.UserAgent(), twoFactor); err != nil {
+		return err
+	}
+
+	// A new sign-in on this browser replaces its previous session
+	if previous := currentSessionHash(r); previous != "" {
+		if _, err := db.Exec(`
+			UPDATE user_sessions SET revoked_at = NOW() WHERE token_hash=$1 AND revoked_at IS NULL
+		`, previous); err != nil {
+			log.Printf("Error revoking session: %v", err)
+		}
+	}
+
+	session, _ := store.Get(r, "auth")
+	delete(session.Values, "pending_login")
+	session.Values["sid"] = sid
+	session.Values["user_id"] = userID
+	session.Values["role"] = role
+	session.Values["username"] = username
+	return session.Save(r, w)
+}
+
+// currentSessionHash identifies the session row of the request's cookie
+func currentSessionHash(r *http.Request) string {
+	session, err := store.Get(r, "auth")
+	if err != nil {
+		return ""
+	}
+	sid, _ := session.Values["sid"].(string)
+	if sid == "" {
+		return ""
+	}
+	return hashToken(sid)
+}
+
+// touchSession checks that the session of r is still active and records the
+// activity, returning the role of its user and whether the session passed a
+// second factor.
+func touchSession(r *http.Request, userID int) (role string, twoFactor bool, err error) {
+	err = db.QueryRow(`
+		UPDATE user_sessions s SET last_seen_at = NOW(), ip = $3
+		FROM users u
+		WHERE u.id = s.user_id AND s.user_id=$1 AND s.token_hash=$2 AND `+activeSession("$4", "$5")+`
+		RETURNING u.role, s.two_factor
+	`, userID, currentSessionHash(r), clientIP(r),
+		sessionIdleTimeout.Seconds(), sessionMaxAge.Seconds()).Scan(&role, &twoFactor)
+	return role, twoFactor, err
+}
+
+// revokeSessions ends the sessions of userID, except the one with keepHash
+// when it is not empty.
+func revokeSessions(ex execer, userID int, keepHash string) error {
+	_, err := ex.Exec(`
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id=$1 AND revoked_at IS NULL AND token_hash <> $2
+	`, userID, keepHash)
+	return err
+}
+
+// sessionsHandler godoc
+// @Summary My sessions
+// @Description Signed-in user: the browsers and devices signed in to their account (GET), or sign one out by ?id= or every other one with ?all=true (DELETE). Sessions end after SESSION_IDLE_TIMEOUT without activity and 24 hours after sign-in.
+// @Tags Auth
+// @Produce json
+// @Security SessionAuth
+// @Param id query int false "Session ID (DELETE)"
+// @Param all query bool false "Every session but this one (DELETE)"
+// @Success 200 {array} UserSession
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /sessions [get]
+// @Router /sessions [delete]
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUserID(r)
+	current := currentSessionHash(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`
+			SELECT s.id, s.created_at, s.last_seen_at, COALESCE(s.ip, ''), COALESCE(s.user_agent, ''),
+				s.token_hash = $2
+			FROM user_sessions s
+			WHERE s.user_id=$1 AND `+activeSession("$3", "$4")+`
+			ORDER BY s.last_seen_at DESC`,
+			userID, current, sessionIdleTimeout.Seconds(), sessionMaxAge.Seconds())
+		if err != nil {
+			log.Printf("Error fetching sessions: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		sessions := []UserSession{}
+		for rows.Next() {
+			var s UserSession
+			var created, seen time.Time
+			if err := rows.Scan(&s.ID, &created, &seen, &s.IP, &s.UserAgent, &s.Current); err != nil {
+				log.Printf("Error scanning session: %v", err)
+				continue
+			}
+			s.CreatedAt = created.Format(time.RFC3339)
+			s.LastSeenAt = seen.Format(time.RFC3339)
+			sessions = append(sessions, s)
+		}
+		respondJSON(w, sessions, http.StatusOK)
+
+	case http.MethodDelete:
+		if r.URL.Query().Get("all") == "true" {
+			if err := revokeSessions(db, userID, current); err != nil {
+				log.Printf("Error revoking sessions: %v", err)
+				respondError(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+			return
+		}
+
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			respondError(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+		var userAgent string
+		err = db.QueryRow(`
+			UPDATE user_sessions SET revoked_at = NOW()
+			WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL
+			RETURNING COALESCE(user_agent, '')
+		`, id, userID).Scan(&userAgent)
+		if err == sql.ErrNoRows {
+			respondError(w, "Session not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Error revoking session: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "user_sessions", strconv.Itoa(id), map[string]string{"user_agent": userAgent}, nil)
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	_ "time/tzdata" // the alpine runtime image ships without zoneinfo
+)
+
+// StatsBucket is one group of a breakdown or one period of a time series
+type StatsBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	Delta *int   `json:"delta,omitempty"`
+}
+
+// FunnelStage counts applications currently at a status and those that got
+// at least that far in the review funnel.
+type FunnelStage struct {
+	Status     string  `json:"status"`
+	Count      int     `json:"count"`
+	Reached    int     `json:"reached"`
+	Conversion float64 `json:"conversion"`
+}
+
+// PeriodComparison compares the current week so far with the same span of
+// the previous week.
+type PeriodComparison struct {
+	Current  int      `json:"current"`
+	Previous int      `json:"previous"`
+	Delta    int      `json:"delta"`
+	DeltaPct *float64 `json:"delta_pct,omitempty"`
+}
+
+// ApplicationStats is the payload of the stats endpoint
+type ApplicationStats struct {
+	Interval     string                   `json:"interval"`
+	Timezone     string                   `json:"timezone"`
+	From         string                   `json:"from"`
+	To           string                   `json:"to"`
+	Total        int                      `json:"total"`
+	TimeSeries   []StatsBucket            `json:"time_series"`
+	Breakdowns   map[string][]StatsBucket `json:"breakdowns"`
+	Funnel       []FunnelStage            `json:"funnel"`
+	WeekOverWeek PeriodComparison         `json:"week_over_week"`
+}
+
+var statsIntervals = map[string]string{"daily": "day", "weekly": "week", "monthly": "month"}
+
+// statsDimensions maps breakdown names to application columns
+var statsDimensions = []struct{ name, column string }{
+	{"university", "a.university"},
+	{"field_of_study", "a.field_of_study"},
+	{"degree_level", "a.degree_level"},
+	{"application_type", "a.application_type"},
+	{"preferred_working_method", "a.preferred_working_method"},
+	{"status", "a.status"},
+}
+
+// funnelStatuses are the forward stages; rejected and withdrawn applications
+// only count towards "submitted" since the stage they left at is unknown.
+var funnelStatuses = []string{StatusSubmitted, StatusUnderReview, StatusShortlisted, StatusInterview, StatusAccepted}
+
+type statsQuery struct {
+	where string
+	args  []interface{}
+}
+
+// param appends a bind value and returns its placeholder
+func (q *statsQuery) param(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// filtered is a CTE selecting the applications the stats are computed over
+func (q *statsQuery) filtered(from, to time.Time) string {
+	return `WITH filtered AS (
+		SELECT a.* FROM applications a` + q.where + andOrWhere(q.where) +
+		`a.created_at >= ` + q.param(from) + ` AND a.created_at < ` + q.param(to) + `
+	)`
+}
+
+func andOrWhere(where string) string {
+	if where == "" {
+		return " WHERE "
+	}
+	return " AND "
+}
+
+func scanBuckets(q *statsQuery, query string) ([]StatsBucket, error) {
+	rows, err := db.Query(query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []StatsBucket{}
+	for rows.Next() {
+		var b StatsBucket
+		if err := rows.Scan(&b.Key, &b.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// applicationStats godoc
+// @Summary Application statistics
+// @Description Admin: time series, breakdowns, status funnel and week-over-week change, computed in the given timezone. Accepts the list endpoint filters.
+// @Tags Admin
+// @Produce json
+// @Security SessionAuth
+// @Param interval query string false "daily, weekly (default) or monthly"
+// @Param tz query string false "IANA timezone, default UTC"
+// @Param from query string false "First day (YYYY-MM-DD), default 12 intervals ago"
+// @Param to query string false "Last day (YYYY-MM-DD), default today"
+// @Param campaign_id query int false "Campaign ID"
+// @Success 200 {object} ApplicationStats
+// @Failure 400 {string} string
+// @Router /stats [get]
+func applicationStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	stats := ApplicationStats{
+		Interval: params.Get("interval"),
+		Timezone: params.Get("tz"),
+	}
+	if stats.Interval == "" {
+		stats.Interval = "weekly"
+	}
+	if stats.Timezone == "" {
+		stats.Timezone = "UTC"
+	}
+
+	unit, ok := statsIntervals[stats.Interval]
+	if !ok {
+		respondError(w, "Invalid interval", http.StatusBadRequest)
+		return
+	}
+	loc, err := time.LoadLocation(stats.Timezone)
+	if err != nil {
+		respondError(w, "Invalid timezone", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	to := today
+	if v := params.Get("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			respondError(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+	var from time.Time
+	if v := params.Get("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			respondError(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+	} else {
+		switch unit {
+		case "day":
+			from = to.AddDate(0, 0, -11)
+		case "week":
+			from = to.AddDate(0, 0, -7*11)
+		default:
+			from = to.AddDate(0, -11, 0)
+		}
+	}
+	if from.After(to) {
+		respondError(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+	stats.From = from.Format("2006-01-02")
+	stats.To = to.Format("2006-01-02")
+	end := to.AddDate(0, 0, 1)
+
+	// from/to are handled here in the requested timezone
+	params.Del("from")
+	params.Del("to")
+	where, args, err := applicationFilters(params)
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newQuery := func() *statsQuery {
+		return &statsQuery{where: where, args: append([]interface{}{}, args...)}
+	}
+
+	// Time series with empty periods filled in
+	q := newQuery()
+	cte := q.filtered(from, end)
+	tz, u := q.param(stats.Timezone)+"::TEXT", q.param(unit)+"::TEXT"
+	series, err := scanBuckets(q, cte+`
+		SELECT TO_CHAR(p.period, 'YYYY-MM-DD'), COUNT(a.id)
+		FROM GENERATE_SERIES(
+			DATE_TRUNC(`+u+`, `+q.param(from)+`::TIMESTAMPTZ AT TIME ZONE `+tz+`),
+			DATE_TRUNC(`+u+`, `+q.param(to)+`::TIMESTAMPTZ AT TIME ZONE `+tz+`),
+			('1 ' || `+u+`)::INTERVAL
+		) AS p(period)
+		LEFT JOIN filtered a ON DATE_TRUNC(`+u+`, a.created_at AT TIME ZONE `+tz+`) = p.period
+		GROUP BY p.period
+		ORDER BY p.period`)
+	if err != nil {
+		log.Printf("Error computing time series: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for i := range series {
+		stats.Total += series[i].Count
+		if i > 0 {
+			d := series[i].Count - series[i-1].Count
+			series[i].Delta = &d
+		}
+	}
+	stats.TimeSeries = series
+
+	// Breakdowns
+	stats.Breakdowns = map[string][]StatsBucket{}
+	for _, dim := range statsDimensions {
+		q := newQuery()
+		buckets, err := scanBuckets(q, q.filtered(from, end)+`
+			SELECT COALESCE(NULLIF(`+dim.column+`, ''), '(none)'), COUNT(*)
+			FROM filtered a
+			GROUP BY 1 ORDER BY 2 DESC, 1`)
+		if err != nil {
+			log.Printf("Error computing %s breakdown: %v", dim.name, err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		stats.Breakdowns[dim.name] = buckets
+	}
+
+	q = newQuery()
+	subjects, err := scanBuckets(q, q.filtered(from, end)+`
+		SELECT s.name, COUNT(*)
+		FROM filtered a
+		JOIN application_subjects aps ON aps.application_id = a.id
+		JOIN subjects s ON s.id = aps.subject_id
+		GROUP BY s.name ORDER BY 2 DESC, 1`)
+	if err != nil {
+		log.Printf("Error computing subject breakdown: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	stats.Breakdowns["subject"] = subjects
+
+	// Funnel: cumulative counts from the furthest stage backwards
+	counts := map[string]int{}
+	for _, b := range stats.Breakdowns["status"] {
+		counts[b.Key] = b.Count
+	}
+	reached := 0
+	stats.Funnel = make([]FunnelStage, len(funnelStatuses))
+	for i := len(funnelStatuses) - 1; i >= 0; i-- {
+		status := funnelStatuses[i]
+		reached += counts[status]
+		if status == StatusSubmitted {
+			reached = stats.Total
+		}
+		stage := FunnelStage{Status: status, Count: counts[status], Reached: reached}
+		if stats.Total > 0 {
+			stage.Conversion = float64(reached) / float64(stats.Total)
+		}
+		stats.Funnel[i] = stage
+	}
+
+	// Week over week, independent of the requested range
+	q = newQuery()
+	tz = q.param(stats.Timezone) + "::TEXT"
+	err = db.QueryRow(`
+		WITH bounds AS (
+			SELECT DATE_TRUNC('week', NOW() AT TIME ZONE `+tz+`) AT TIME ZONE `+tz+` AS week_start
+		)
+		SELECT
+			COUNT(*) FILTER (WHERE a.created_at >= b.week_start),
+			COUNT(*) FILTER (WHERE a.created_at >= b.week_start - INTERVAL '7 days'
+				AND a.created_at < NOW() - INTERVAL '7 days')
+		FROM applications a, bounds b`+q.where+andOrWhere(q.where)+`a.created_at >= b.week_start - INTERVAL '7 days'`,
+		q.args...).Scan(&stats.WeekOverWeek.Current, &stats.WeekOverWeek.Previous)
+	if err != nil {
+		log.Printf("Error computing week over week: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	wow := &stats.WeekOverWeek
+	wow.Delta = wow.Current - wow.Previous
+	if wow.Previous > 0 {
+		pct := float64(wow.Delta) / float64(wow.Previous) * 100
+		wow.DeltaPct = &pct
+	}
+
+	respondJSON(w, stats, http.StatusOK)
+}
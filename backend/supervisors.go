@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// redactSupervisorPII hides the phone number and gender of candidates from
+// supervisors, who only need the academic profile to judge a fit.
+var redactSupervisorPII = getEnv("REDACT_SUPERVISOR_PII", "true") == "true"
+
+// supervisedCondition matches applications over "a" referencing a subject
+// owned by the user bound to "?".
+const supervisedCondition = `EXISTS (
+	SELECT 1 FROM application_subjects aps
+	JOIN subject_owners so ON so.subject_id = aps.subject_id
+	WHERE aps.application_id = a.id AND so.user_id = ?)`
+
+// applicationScope describes which applications the session user may see.
+// Supervisors are limited to the applications for the subjects they own
+// (see the reviewer pool), everyone else sees all of them.
+type applicationScope struct {
+	supervisorID int
+	redact       bool
+}
+
+func scopeOf(r *http.Request) applicationScope {
+	userID, role, ok := currentRole(r)
+	if !ok || role != RoleSupervisor {
+		return applicationScope{}
+	}
+	return applicationScope{supervisorID: userID, redact: redactSupervisorPII}
+}
+
+func (s applicationScope) restricted() bool {
+	return s.supervisorID != 0
+}
+
+// filter narrows a WHERE clause built by applicationFilters to the scope
+func (s applicationScope) filter(where string, args []interface{}) (string, []interface{}) {
+	if !s.restricted() {
+		return where, args
+	}
+	args = append(args, s.supervisorID)
+	cond := strings.ReplaceAll(supervisedCondition, "?", "$"+strconv.Itoa(len(args)))
+	if where == "" {
+		return " WHERE " + cond, args
+	}
+	return where + " AND " + cond, args
+}
+
+// redactAll blanks the personal fields supervisors may not see
+func (s applicationScope) redactAll(apps []ApplicationResponse) {
+	if !s.redact {
+		return
+	}
+	for i := range apps {
+		apps[i].Phone = ""
+		apps[i].Gender = ""
+	}
+}
+
+// allows reports whether the application is within the scope. Unknown
+// applications are allowed, so callers keep answering them with a 404.
+func (s applicationScope) allows(appID int) (bool, error) {
+	if !s.restricted() {
+		return true, nil
+	}
+	var ok bool
+	err := db.QueryRow(`
+		SELECT NOT EXISTS (SELECT 1 FROM applications WHERE id=$1)
+		OR EXISTS (SELECT 1 FROM applications a WHERE a.id=$1 AND `+
+		strings.ReplaceAll(supervisedCondition, "?", "$2")+`)
+	`, appID, s.supervisorID).Scan(&ok)
+	return ok, err
+}
+
+// documentOf finds the application within the scope that an uploaded file
+// belongs to, and whether it is its "cv" or "motivation" letter. It returns
+// sql.ErrNoRows when there is none.
+func (s applicationScope) documentOf(path string) (appID int, kind string, err error) {
+	cond, args := "TRUE", []interface{}{path}
+	if s.restricted() {
+		cond, args = strings.ReplaceAll(supervisedCondition, "?", "$2"), append(args, s.supervisorID)
+	}
+	err = db.QueryRow(`
+		SELECT a.id, CASE WHEN a.cv_file_path=$1 THEN 'cv' ELSE 'motivation' END
+		FROM applications a
+		WHERE (a.cv_file_path=$1 OR a.motivation_file_path=$1) AND `+cond+`
+		ORDER BY a.id LIMIT 1
+	`, args...).Scan(&appID, &kind)
+	return appID, kind, err
+}
+
+// requireApplication answers 404 for applications outside the scope of the
+// session user, so supervisors cannot probe for other applications. It
+// reports whether the handler may go on.
+func requireApplication(w http.ResponseWriter, r *http.Request, appID int) bool {
+	ok, err := scopeOf(r).allows(appID)
+	if err != nil {
+		log.Printf("Error checking application scope: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		respondError(w, "Application not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// tokenSecret signs link tokens. main refuses to start without it: with a
+// known key anyone could forge them.
+var tokenSecret = []byte(os.Getenv("TOKEN_SECRET"))
+
+// sessionSecret keys the session cookies, which name the signed-in user or
+// candidate. It is just as required.
+var sessionSecret = []byte(os.Getenv("SESSION_SECRET"))
+
+// newCookieStore signs and encrypts cookies with keys derived from
+// sessionSecret, a different one for each use.
+func newCookieStore() *sessions.CookieStore {
+	return sessions.NewCookieStore(deriveKey(sessionSecret, "cookie signing"), deriveKey(sessionSecret, "cookie encryption"))
+}
+
+func deriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how secrets handed out to users (login links, session ids)
+// are stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// tokenClaims is the payload of a signed link token. Purpose keeps a token
+// issued for one flow from being replayed against another.
+type tokenClaims struct {
+	Purpose string `json:"p"`
+	Subject int    `json:"s"`
+	Expires int64  `json:"e"`
+}
+
+// signToken returns a URL-safe "payload.signature" token for subject that is
+// valid for ttl.
+func signToken(purpose string, subject int, ttl time.Duration) string {
+	payload, _ := json.Marshal(tokenClaims{
+		Purpose: purpose,
+		Subject: subject,
+		Expires: time.Now().Add(ttl).Unix(),
+	})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + tokenSignature(encoded)
+}
+
+// verifyToken checks the signature, purpose and expiry of token and returns
+// its subject.
+func verifyToken(purpose, token string) (int, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(tokenSignature(encoded))) {
+		return 0, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, errInvalidToken
+	}
+
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return 0, errInvalidToken
+	}
+	if claims.Purpose != purpose || time.Now().Unix() > claims.Expires {
+		return 0, errInvalidToken
+	}
+	return claims.Subject, nil
+}
+
+func tokenSignature(encoded string) string {
+	mac := hmac.New(sha256.New, tokenSecret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
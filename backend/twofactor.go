@@ -0,0 +1,772 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TOTP as set up by authenticator apps: SHA-1, 6 digits, 30 second steps.
+// One step of clock drift is tolerated either way.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1
+
+	recoveryCodeCount = 10
+
+	// A password-verified login waits this long for its second factor, and
+	// gets this many tries.
+	twoFactorLoginTTL    = 5 * time.Minute
+	maxTwoFactorAttempts = 5
+)
+
+var totpIssuer = getEnv("TOTP_ISSUER", "PFE Portal")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorStatus is the two-factor setup of the signed-in user
+type TwoFactorStatus struct {
+	Enabled       bool `json:"enabled"`
+	Required      bool `json:"required"`
+	RecoveryCodes int  `json:"recovery_codes_left"`
+}
+
+// totpCode is the HOTP value of secret for a time step (RFC 4226)
+func totpCode(secret []byte, step int64) string {
+	mac := hmac.New(sha1.New, secret)
+	binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the time step code is valid for, if it is later than the
+// last step already used, so a code cannot be replayed.
+func matchTOTP(secret, code string, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step > lastStep && hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpURI is the otpauth:// provisioning URI authenticator apps read from a
+// QR code
+func totpURI(secret, username string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(totpDigits))
+	q.Set("period", strconv.Itoa(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+username) + "?" + q.Encode()
+}
+
+// normalizeRecoveryCode ignores case and the dash codes are shown with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// issueRecoveryCodes replaces the recovery codes of userID and returns the
+// new ones, which are only stored hashed.
+func issueRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		if _, err := tx.Exec(`
+			INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, hashToken(normalizeRecoveryCode(code))); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code of
+// userID, which is then used up.
+func checkSecondFactor(ex execer, userID int, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+
+	var secret sql.NullString
+	var lastStep int64
+	if err := db.QueryRow(`
+		SELECT totp_secret, totp_last_step FROM users WHERE id=$1
+	`, userID).Scan(&secret, &lastStep); err != nil {
+		return false, err
+	}
+	if step, ok := matchTOTP(secret.String, code, lastStep); ok {
+		res, err := ex.Exec(`
+			UPDATE users SET totp_last_step=$1 WHERE id=$2 AND totp_last_step < $1
+		`, step, userID)
+		if err != nil {
+			return false, err
+		}
+		n, _ := res.RowsAffected()
+		return n == 1, nil
+	}
+
+	res, err := ex.Exec(`
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+	`, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// roleRequiresTwoFactor reports whether holders of role must use 2FA
+func roleRequiresTwoFactor(role string) (bool, error) {
+	var required bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM mfa_required_roles WHERE role=$1)
+	`, role).Scan(&required)
+	return required, err
+}
+
+// twoFactorSatisfied reports whether the session may act with role: either
+// it passed a second factor, as verified says, or the role does not need
+// one. API tokens are created from such a session and stand on their own.
+func twoFactorSatisfied(r *http.Request, role string, verified bool) bool {
+	if verified || apiTokenOf(r) != nil {
+		return true
+	}
+	required, err := roleRequiresTwoFactor(role)
+	if err != nil {
+		log.Printf("Error checking two-factor policy: %v", err)
+		return false
+	}
+	return !required
+}
+
+// startTwoFactorLogin parks a password-verified login until the second
+// factor is given to loginTwoFactor. The session is not signed in meanwhile;
+// it only names the pending login, which is kept with its failed attempts in
+// two_factor_logins.
+func startTwoFactorLogin(w http.ResponseWriter, r *http.Request, userID int) error {
+	token, err := randomString()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM two_factor_logins WHERE expires_at < NOW()`); err != nil {
+		log.Printf("Error cleaning up two-factor logins: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO two_factor_logins (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	`, userID, hashToken(token), time.Now().Add(twoFactorLoginTTL)); err != nil {
+		return err
+	}
+
+	session, _ := store.Get(r, "auth")
+	for _, key := range []string{"user_id", "role", "username"} {
+		delete(session.Values, key)
+	}
+	session.Values["pending_login"] = token
+	return session.Save(r, w)
+}
+
+// loginTwoFactor godoc
+// @Summary Second login step
+// @Description Completes a login that answered two_factor_required, with a code from the authenticator app or a recovery code
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body object{code=string} true "Code payload"
+// @Success 200 {object} map[string]bool
+// @Failure 401 {string} string
+// @Router /login/2fa [post]
+func loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	session, _ := store.Get(r, "auth")
+	token, _ := session.Values["pending_login"].(string)
+	if token == "" {
+		respondError(w, "Login expired, please sign in again", http.StatusUnauthorized)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// The row lock makes concurrent guesses wait for each other's count
+	var loginID, userID int
+	var role, username string
+	err = tx.QueryRow(`
+		SELECT l.id, l.user_id, u.role, u.username
+		FROM two_factor_logins l JOIN users u ON u.id = l.user_id
+		WHERE l.token_hash=$1 AND l.expires_at > NOW() AND l.attempts < $2 AND u.totp_enabled
+		FOR UPDATE OF l
+	`, hashToken(token), maxTwoFactorAttempts).Scan(&loginID, &userID, &role, &username)
+	if err == sql.ErrNoRows {
+		respondError(w, "Login expired, please sign in again", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching two-factor login: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	valid, err := checkSecondFactor(tx, userID, body.Code)
+	if err != nil {
+		log.Printf("Error checking second factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if valid {
+		_, err = tx.Exec(`DELETE FROM two_factor_logins WHERE id=$1`, loginID)
+	} else {
+		_, err = tx.Exec(`UPDATE two_factor_logins SET attempts = attempts + 1 WHERE id=$1`, loginID)
+	}
+	if err != nil {
+		log.Printf("Error updating two-factor login: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing two-factor login: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		auditLogin(r, &userID, username, http.StatusUnauthorized)
+		respondError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := startSession(w, r, userID, role, username, true); err != nil {
+		log.Printf("Error saving session: %v", err)
+		respondError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	auditLogin(r, &userID, username, http.StatusOK)
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// twoFactorHandler godoc
+// @Summary My two-factor setup
+// @Description Signed-in user: whether 2FA is enabled, whether their role requires it, and how many recovery codes are left
+// @Tags Two-factor
+// @Produce json
+// @Security SessionAuth
+// @Success 200 {object} TwoFactorStatus
+// @Router /2fa [get]
+func twoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, role, _ := currentRole(r)
+	var s TwoFactorStatus
+	if err := db.QueryRow(`
+		SELECT u.totp_enabled,
+			EXISTS (SELECT 1 FROM mfa_required_roles WHERE role=$2),
+			(SELECT COUNT(*) FROM recovery_codes WHERE user_id = u.id AND used_at IS NULL)
+		FROM users u WHERE u.id=$1
+	`, userID, role).Scan(&s.Enabled, &s.Required, &s.RecoveryCodes); err != nil {
+		log.Printf("Error fetching two-factor status: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, s, http.StatusOK)
+}
+
+// enrollTwoFactor godoc
+// @Summary Start two-factor enrollment
+// @Description Signed-in user: after checking the password, except for SSO accounts which have none, generates a TOTP secret and its otpauth:// URI for a QR code. 2FA is only turned on once a code is confirmed with /2fa/enable.
+// @Tags Two-factor
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{password=string} true "Password payload"
+// @Success 200 {object} map[string]string
+// @Failure 403 {string} string
+// @Failure 409 {string} string
+// @Router /2fa/enroll [post]
+func enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	var username, hash string
+	var enabled, sso bool
+	if err := db.QueryRow(`
+		SELECT username, password_hash, totp_enabled, oidc_subject IS NOT NULL FROM users WHERE id=$1
+	`, userID).Scan(&username, &hash, &enabled, &sso); err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	// SSO accounts have no password, their session is all there is
+	if ok, _ := verifyPassword(hash, body.Password); !ok && !sso {
+		respondError(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+	if enabled {
+		respondError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		respondError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := db.Exec(`
+		UPDATE users SET totp_secret=$1, totp_last_step=0 WHERE id=$2 AND NOT totp_enabled
+	`, secret, userID); err != nil {
+		log.Printf("Error storing TOTP secret: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]string{
+		"secret": secret,
+		"uri":    totpURI(secret, username),
+	}, http.StatusOK)
+}
+
+// enableTwoFactor godoc
+// @Summary Turn on two-factor authentication
+// @Description Signed-in user: confirms enrollment with a code from the authenticator app and returns recovery codes, which are only shown this once
+// @Tags Two-factor
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{code=string} true "Code payload"
+// @Success 200 {object} map[string][]string
+// @Failure 400 {string} string
+// @Failure 409 {string} string
+// @Router /2fa/enable [post]
+func enableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var secret sql.NullString
+	var enabled bool
+	if err := tx.QueryRow(`
+		SELECT totp_secret, totp_enabled FROM users WHERE id=$1 FOR UPDATE
+	`, userID).Scan(&secret, &enabled); err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if enabled {
+		respondError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if !secret.Valid {
+		respondError(w, "Start the enrollment first", http.StatusBadRequest)
+		return
+	}
+	step, ok := matchTOTP(secret.String, strings.TrimSpace(body.Code), 0)
+	if !ok {
+		respondError(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users SET totp_enabled = TRUE, totp_last_step=$1 WHERE id=$2
+	`, step, userID); err != nil {
+		log.Printf("Error enabling two-factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	// The code just given counts as this session's second factor
+	if _, err := tx.Exec(`
+		UPDATE user_sessions SET two_factor = TRUE WHERE user_id=$1 AND token_hash=$2
+	`, userID, currentSessionHash(r)); err != nil {
+		log.Printf("Error enabling two-factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	codes, err := issueRecoveryCodes(tx, userID)
+	if err != nil {
+		log.Printf("Error issuing recovery codes: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing two-factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string][]string{"recovery_codes": codes}, http.StatusOK)
+}
+
+// disableTwoFactor godoc
+// @Summary Turn off two-factor authentication
+// @Description Signed-in user: turns 2FA off after checking the password (except for SSO accounts) and a current code. Not allowed while the user's role requires 2FA.
+// @Tags Two-factor
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{password=string,code=string} true "Credentials payload"
+// @Success 200 {object} map[string]bool
+// @Failure 403 {string} string
+// @Router /2fa/disable [post]
+func disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, role, _ := currentRole(r)
+	required, err := roleRequiresTwoFactor(role)
+	if err != nil {
+		log.Printf("Error checking two-factor policy: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if required {
+		respondError(w, "Your role requires two-factor authentication", http.StatusForbidden)
+		return
+	}
+
+	var hash string
+	var sso bool
+	if err := db.QueryRow(`
+		SELECT password_hash, oidc_subject IS NOT NULL FROM users WHERE id=$1
+	`, userID).Scan(&hash, &sso); err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if ok, _ := verifyPassword(hash, body.Password); !ok && !sso {
+		respondError(w, "Password is incorrect", http.StatusForbidden)
+		return
+	}
+	if ok, err := checkSecondFactor(db, userID, body.Code); err != nil {
+		log.Printf("Error checking second factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		respondError(w, "Invalid code", http.StatusForbidden)
+		return
+	}
+
+	if err := clearTwoFactor(userID, false); err != nil {
+		log.Printf("Error disabling two-factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// regenerateRecoveryCodes godoc
+// @Summary New recovery codes
+// @Description Signed-in user with 2FA: replaces all recovery codes after checking a current code
+// @Tags Two-factor
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{code=string} true "Code payload"
+// @Success 200 {object} map[string][]string
+// @Failure 403 {string} string
+// @Router /2fa/recovery-codes [post]
+func regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := currentUserID(r)
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var enabled bool
+	if err := tx.QueryRow(`SELECT totp_enabled FROM users WHERE id=$1 FOR UPDATE`, userID).Scan(&enabled); err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !enabled {
+		respondError(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	if ok, err := checkSecondFactor(tx, userID, body.Code); err != nil {
+		log.Printf("Error checking second factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	} else if !ok {
+		respondError(w, "Invalid code", http.StatusForbidden)
+		return
+	}
+
+	codes, err := issueRecoveryCodes(tx, userID)
+	if err != nil {
+		log.Printf("Error issuing recovery codes: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing recovery codes: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string][]string{"recovery_codes": codes}, http.StatusOK)
+}
+
+// clearTwoFactor turns 2FA off for userID and drops its secret and recovery
+// codes. signOut also ends every session of the user.
+func clearTwoFactor(userID int, signOut bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = 0 WHERE id=$1
+	`, userID); err != nil {
+		return err
+	}
+	if signOut {
+		if err := revokeSessions(tx, userID, ""); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resetTwoFactor godoc
+// @Summary Reset a user's two-factor authentication
+// @Description Users with users:manage: turns 2FA off for another account that lost its device, and signs it out everywhere. The user can then enroll again.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int} true "User payload"
+// @Success 200 {object} map[string]bool
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /users/2fa/reset [post]
+func resetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if userID, _ := currentUserID(r); userID == body.ID {
+		respondError(w, "You cannot reset your own two-factor authentication", http.StatusConflict)
+		return
+	}
+
+	var enabled bool
+	if err := db.QueryRow(`SELECT totp_enabled FROM users WHERE id=$1`, body.ID).Scan(&enabled); err == sql.ErrNoRows {
+		respondError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := clearTwoFactor(body.ID, true); err != nil {
+		log.Printf("Error resetting two-factor: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	auditChange(r, "users", strconv.Itoa(body.ID),
+		map[string]bool{"two_factor": enabled}, map[string]bool{"two_factor": false})
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// twoFactorPolicy godoc
+// @Summary Roles that require two-factor authentication
+// @Description Users with users:manage: the roles whose holders must sign in with 2FA (GET), or replace them (PUT). Until they enroll, such users can only reach their own 2FA setup.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{roles=[]string} false "Policy payload (PUT)"
+// @Success 200 {object} map[string][]string
+// @Failure 400 {string} string
+// @Router /2fa/policy [get]
+// @Router /2fa/policy [put]
+func twoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		roles, err := requiredTwoFactorRoles(db)
+		if err != nil {
+			log.Printf("Error fetching two-factor policy: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, map[string][]string{"roles": roles}, http.StatusOK)
+
+	case http.MethodPut:
+		var body struct {
+			Roles []string `json:"roles"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		for _, role := range body.Roles {
+			if !validRole(role) {
+				respondError(w, "Unknown role", http.StatusBadRequest)
+				return
+			}
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Printf("Error starting transaction: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		before, err := requiredTwoFactorRoles(tx)
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM mfa_required_roles`)
+		}
+		if err == nil && len(body.Roles) > 0 {
+			_, err = tx.Exec(`
+				INSERT INTO mfa_required_roles (role) SELECT DISTINCT UNNEST($1::TEXT[])
+			`, pq.Array(body.Roles))
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Error updating two-factor policy: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		auditChange(r, "mfa_required_roles", "",
+			map[string][]string{"roles": before}, map[string][]string{"roles": body.Roles})
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// requiredTwoFactorRoles lists the roles that must use 2FA
+func requiredTwoFactorRoles(q queryer) ([]string, error) {
+	var roles pq.StringArray
+	err := q.QueryRow(`
+		SELECT COALESCE(ARRAY_AGG(role ORDER BY role), '{}') FROM mfa_required_roles
+	`).Scan(&roles)
+	return []string(roles), err
+}
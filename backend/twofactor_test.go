@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// The SHA-1 vectors of RFC 6238 appendix B, cut to the last six digits
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	secret := totpEncoding.EncodeToString(key)
+
+	// Keep clear of a step boundary so "now" is the same here and in matchTOTP
+	if time.Now().Unix()%totpPeriod >= totpPeriod-2 {
+		time.Sleep(3 * time.Second)
+	}
+	now := time.Now().Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", secret, totpCode(key, now), 0, now, true},
+		{"previous step", secret, totpCode(key, now-1), 0, now - 1, true},
+		{"next step", secret, totpCode(key, now+1), 0, now + 1, true},
+		{"too old", secret, totpCode(key, now-2), 0, 0, false},
+		{"too far ahead", secret, totpCode(key, now+2), 0, 0, false},
+		{"replayed", secret, totpCode(key, now), now, 0, false},
+		{"older than last used", secret, totpCode(key, now-1), now, 0, false},
+		{"later than last used", secret, totpCode(key, now+1), now, now + 1, true},
+		{"wrong length", secret, totpCode(key, now)[1:], 0, 0, false},
+		{"invalid secret", "not base32!", totpCode(key, now), 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := matchTOTP(tt.secret, tt.code, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("matchTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
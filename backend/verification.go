@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	emailVerificationPurpose = "email-verification"
+	emailVerificationTTL     = 48 * time.Hour
+)
+
+// verificationResendInterval is how long an account waits between two
+// verification emails
+var verificationResendInterval = parseDuration(getEnv("VERIFICATION_RESEND_INTERVAL", "2m"))
+
+// validEmail accepts a bare address such as jane@example.com
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// sendVerificationEmail queues the verification link of userID
+func sendVerificationEmail(ex execer, userID int, email string) error {
+	return enqueueEmail(ex, email, "verify_email", map[string]interface{}{
+		"Link":      appURL + "/verify-email?token=" + url.QueryEscape(signToken(emailVerificationPurpose, userID, emailVerificationTTL)),
+		"ExpiresIn": "48 hours",
+	})
+}
+
+// verifyEmail godoc
+// @Summary Verify an email address
+// @Description Marks the account of the token from the verification email as verified, which lets it sign in
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body object{token=string} true "Token payload"
+// @Success 200 {object} map[string]bool
+// @Failure 401 {string} string
+// @Router /verify-email [post]
+func verifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	userID, err := verifyToken(emailVerificationPurpose, body.Token)
+	if err != nil {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	}
+
+	// Opening the link again is harmless and keeps the first date
+	res, err := db.Exec(`
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id=$1
+	`, userID)
+	if err != nil {
+		log.Printf("Error verifying email: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		respondError(w, "Invalid or expired link", http.StatusUnauthorized)
+		return
+	}
+	recordAudit(r, http.StatusOK, "email.verify", "users", strconv.Itoa(userID), nil, nil)
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
+
+// resendVerification godoc
+// @Summary Resend the verification email
+// @Description Sends a new verification link to an unverified account, at most once per VERIFICATION_RESEND_INTERVAL. The response is the same whether or not the account exists, is verified or was sent an email recently.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body object{email=string} true "Email payload"
+// @Success 200 {object} map[string]bool
+// @Failure 400 {string} string
+// @Router /verify-email/resend [post]
+func resendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	email := normalizeEmail(body.Email)
+	if email == "" {
+		respondError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Claiming the send slot and queueing the email together keeps
+	// concurrent requests from sending more than one.
+	var userID int
+	var to string
+	err = tx.QueryRow(`
+		UPDATE users SET verification_sent_at = NOW()
+		WHERE LOWER(email)=$1 AND email_verified_at IS NULL
+			AND (verification_sent_at IS NULL OR verification_sent_at < NOW() - MAKE_INTERVAL(secs => $2))
+		RETURNING id, email
+	`, email, verificationResendInterval.Seconds()).Scan(&userID, &to)
+	if err == sql.ErrNoRows {
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+		return
+	} else if err != nil {
+		log.Printf("Error claiming verification email: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := sendVerificationEmail(tx, userID, to); err != nil {
+		log.Printf("Error queueing verification email: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing verification email: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
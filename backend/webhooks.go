@@ -0,0 +1,586 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook event types
+const (
+	EventApplicationCreated       = "application.created"
+	EventApplicationStatusChanged = "application.status_changed"
+	EventSubjectCreated           = "subject.created"
+	EventSubjectUpdated           = "subject.updated"
+	EventSubjectDeleted           = "subject.deleted"
+)
+
+var webhookEvents = []string{
+	EventApplicationCreated, EventApplicationStatusChanged,
+	EventSubjectCreated, EventSubjectUpdated, EventSubjectDeleted,
+}
+
+const (
+	webhookBatchSize   = 20
+	webhookMaxAttempts = 10
+	webhookLease       = 2 * time.Minute
+)
+
+// webhookClient only connects to public addresses, checked on every dial so
+// that a name resolving to an internal host later, or a redirect, is caught
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}).DialContext,
+	},
+}
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// publicAddress tells whether ip is routable on the internet, as opposed to
+// loopback, private, link-local (cloud metadata) or unspecified addresses
+func publicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// WebhookSubscription is an endpoint that receives signed event payloads.
+// The secret is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID        int      `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// WebhookDelivery is one event sent to one subscription
+type WebhookDelivery struct {
+	ID             int64   `json:"id"`
+	SubscriptionID int     `json:"subscription_id"`
+	Event          string  `json:"event"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	ResponseCode   *int    `json:"response_code,omitempty"`
+	LastError      *string `json:"last_error,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	DeliveredAt    *string `json:"delivered_at,omitempty"`
+	NextAttemptAt  *string `json:"next_attempt_at,omitempty"`
+}
+
+func validWebhookEvents(events []string) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for _, e := range events {
+		found := false
+		for _, known := range webhookEvents {
+			if e == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// enqueueWebhook records a delivery of event for every active subscription
+// that listens to it. Call it with the transaction of the triggering change.
+func enqueueWebhook(ex execer, event, summary string, data interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         randomHex(16),
+		"event":      event,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+		"text":       summary,
+		"data":       data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event, payload)
+		SELECT id, $1, $2 FROM webhook_subscriptions
+		WHERE active AND $1 = ANY(events)
+	`, event, string(payload))
+	return err
+}
+
+// signWebhook computes the X-Webhook-Signature value over "timestamp.body"
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBackoff(attempts int) time.Duration {
+	d := time.Minute << (attempts - 1)
+	if d > 12*time.Hour || d <= 0 {
+		d = 12 * time.Hour
+	}
+	return d
+}
+
+// runWebhookWorker delivers pending webhooks until the process exits
+func runWebhookWorker() {
+	for {
+		n, err := deliverWebhookBatch()
+		if err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+		if n < webhookBatchSize {
+			time.Sleep(outboxPollInterval)
+		}
+	}
+}
+
+func deliverWebhookBatch() (int, error) {
+	// Deliveries of disabled subscriptions are dropped rather than kept pending
+	if _, err := db.Exec(`
+		UPDATE webhook_deliveries d SET status = 'cancelled'
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND NOT s.active AND d.status = 'pending'
+	`); err != nil {
+		return 0, err
+	}
+
+	rows, err := db.Query(`
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $1)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND s.active AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event, d.payload, d.attempts, s.url, s.secret
+	`, webhookLease.Seconds(), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	type delivery struct {
+		id       int64
+		event    string
+		payload  []byte
+		attempts int
+		url      string
+		secret   string
+	}
+	var batch []delivery
+	for rows.Next() {
+		var d delivery
+		if err := rows.Scan(&d.id, &d.event, &d.payload, &d.attempts, &d.url, &d.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, d := range batch {
+		started := time.Now()
+		code, respBody, err := postWebhook(d.id, d.event, d.url, d.secret, d.payload)
+		duration := time.Since(started).Milliseconds()
+
+		var code64 sql.NullInt64
+		if code != 0 {
+			code64 = sql.NullInt64{Int64: int64(code), Valid: true}
+		}
+		var errText sql.NullString
+		if err != nil {
+			errText = sql.NullString{String: err.Error(), Valid: true}
+		}
+
+		if _, dbErr := db.Exec(`
+			INSERT INTO webhook_delivery_attempts (delivery_id, response_code, response_body, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5)
+		`, d.id, code64, respBody, errText, duration); dbErr != nil {
+			log.Printf("Error logging webhook attempt %d: %v", d.id, dbErr)
+		}
+
+		attempts := d.attempts + 1
+		status := "pending"
+		next := webhookBackoff(attempts)
+		if err == nil {
+			status = "delivered"
+		} else if attempts >= webhookMaxAttempts {
+			status = "failed"
+		}
+		if _, dbErr := db.Exec(`
+			UPDATE webhook_deliveries
+			SET status=$1, attempts=$2, response_code=$3, last_error=$4,
+			next_attempt_at = NOW() + make_interval(secs => $5),
+			delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() ELSE delivered_at END
+			WHERE id=$6
+		`, status, attempts, code64, errText, next.Seconds(), d.id); dbErr != nil {
+			log.Printf("Error updating webhook delivery %d: %v", d.id, dbErr)
+		}
+	}
+	return len(batch), nil
+}
+
+// postWebhook sends one signed payload; any non-2xx answer is an error
+func postWebhook(id int64, event, target, secret string, payload []byte) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "internship-platform-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(id, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", signWebhook(secret, timestamp, payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	body := strings.ReplaceAll(strings.ToValidUTF8(string(raw), ""), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, body, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// validWebhookURL accepts http(s) URLs whose host only resolves to public
+// addresses
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !publicAddress(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhooksHandler godoc
+// @Summary Manage webhook subscriptions
+// @Description Admin: list, create, update or delete webhook subscriptions. URLs must resolve to public addresses. The signing secret is generated when omitted and only returned on creation. Pending deliveries of a disabled subscription are cancelled.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int,url=string,secret=string,events=[]string,active=bool} false "Subscription payload (POST/PUT/DELETE)"
+// @Success 200 {array} WebhookSubscription
+// @Failure 400 {string} string
+// @Failure 404 {string} string
+// @Router /webhooks [get]
+// @Router /webhooks [post]
+// @Router /webhooks [put]
+// @Router /webhooks [delete]
+func webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`
+			SELECT id, url, events, active, created_at
+			FROM webhook_subscriptions ORDER BY id
+		`)
+		if err != nil {
+			log.Printf("Error fetching webhooks: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		subs := []WebhookSubscription{}
+		for rows.Next() {
+			var s WebhookSubscription
+			var created time.Time
+			if err := rows.Scan(&s.ID, &s.URL, pq.Array(&s.Events), &s.Active, &created); err != nil {
+				continue
+			}
+			s.CreatedAt = created.Format("2006-01-02")
+			subs = append(subs, s)
+		}
+		respondJSON(w, subs, http.StatusOK)
+
+	case http.MethodPost:
+		var body struct {
+			URL    string   `json:"url"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if !validWebhookEvents(body.Events) {
+			respondError(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+		if !validWebhookURL(body.URL) {
+			respondError(w, "Webhook URL must be an http(s) URL on a public address", http.StatusBadRequest)
+			return
+		}
+		if body.Secret == "" {
+			body.Secret = randomHex(32)
+		}
+
+		s := WebhookSubscription{URL: body.URL, Events: body.Events, Active: true, Secret: body.Secret}
+		var created time.Time
+		err := db.QueryRow(`
+			INSERT INTO webhook_subscriptions (url, secret, events)
+			VALUES ($1, $2, $3) RETURNING id, created_at
+		`, body.URL, body.Secret, pq.Array(body.Events)).Scan(&s.ID, &created)
+		if err != nil {
+			log.Printf("Error creating webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		s.CreatedAt = created.Format("2006-01-02")
+		respondJSON(w, s, http.StatusCreated)
+
+	case http.MethodPut:
+		var body struct {
+			ID     int      `json:"id"`
+			URL    string   `json:"url"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events"`
+			Active bool     `json:"active"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		if body.ID == 0 || !validWebhookEvents(body.Events) {
+			respondError(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+		if !validWebhookURL(body.URL) {
+			respondError(w, "Webhook URL must be an http(s) URL on a public address", http.StatusBadRequest)
+			return
+		}
+
+		res, err := db.Exec(`
+			UPDATE webhook_subscriptions
+			SET url=$1, events=$2, active=$3, secret=COALESCE(NULLIF($4, ''), secret)
+			WHERE id=$5
+		`, body.URL, pq.Array(body.Events), body.Active, body.Secret, body.ID)
+		if err != nil {
+			log.Printf("Error updating webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	case http.MethodDelete:
+		var body struct {
+			ID int `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		res, err := db.Exec(`DELETE FROM webhook_subscriptions WHERE id=$1`, body.ID)
+		if err != nil {
+			log.Printf("Error deleting webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+
+	default:
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// webhookDeliveries godoc
+// @Summary Webhook delivery log
+// @Description Admin: the 100 most recent deliveries, optionally filtered by subscription and status
+// @Tags Webhooks
+// @Produce json
+// @Security SessionAuth
+// @Param subscription_id query int false "Subscription ID"
+// @Param status query string false "pending, delivered, failed or cancelled"
+// @Success 200 {array} WebhookDelivery
+// @Router /webhooks/deliveries [get]
+func webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var subscriptionID sql.NullInt64
+	if v := r.URL.Query().Get("subscription_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, "Invalid subscription id", http.StatusBadRequest)
+			return
+		}
+		subscriptionID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+	status := r.URL.Query().Get("status")
+
+	rows, err := db.Query(`
+		SELECT id, subscription_id, event, status, attempts, response_code,
+		last_error, created_at, delivered_at, next_attempt_at
+		FROM webhook_deliveries
+		WHERE ($1::INT IS NULL OR subscription_id = $1)
+		AND ($2 = '' OR status = $2)
+		ORDER BY id DESC LIMIT 100
+	`, subscriptionID, status)
+	if err != nil {
+		log.Printf("Error fetching webhook deliveries: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		var code sql.NullInt64
+		var lastError sql.NullString
+		var created, next time.Time
+		var delivered sql.NullTime
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Status, &d.Attempts,
+			&code, &lastError, &created, &delivered, &next); err != nil {
+			log.Printf("Error scanning webhook delivery: %v", err)
+			continue
+		}
+		d.CreatedAt = created.Format(time.RFC3339)
+		if code.Valid {
+			c := int(code.Int64)
+			d.ResponseCode = &c
+		}
+		if lastError.Valid {
+			d.LastError = &lastError.String
+		}
+		if delivered.Valid {
+			s := delivered.Time.Format(time.RFC3339)
+			d.DeliveredAt = &s
+		}
+		if d.Status == "pending" {
+			s := next.Format(time.RFC3339)
+			d.NextAttemptAt = &s
+		}
+		deliveries = append(deliveries, d)
+	}
+	respondJSON(w, deliveries, http.StatusOK)
+}
+
+// redeliverWebhook godoc
+// @Summary Redeliver webhook
+// @Description Admin: queue a delivery again immediately, whatever its current status, if its subscription is active
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Security SessionAuth
+// @Param body body object{id=int} true "Delivery payload"
+// @Success 200 {object} map[string]bool
+// @Failure 404 {string} string
+// @Failure 409 {string} string
+// @Router /webhooks/redeliver [post]
+func redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	res, err := db.Exec(`
+		UPDATE webhook_deliveries d
+		SET status='pending', attempts=0, next_attempt_at=NOW()
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND s.active AND d.id=$1
+	`, body.ID)
+	if err != nil {
+		log.Printf("Error redelivering webhook: %v", err)
+		respondError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id=$1)`, body.ID).Scan(&exists); err != nil {
+			log.Printf("Error redelivering webhook: %v", err)
+			respondError(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if exists {
+			respondError(w, "Webhook is disabled", http.StatusConflict)
+		} else {
+			respondError(w, "Delivery not found", http.StatusNotFound)
+		}
+		return
+	}
+	respondJSON(w, map[string]bool{"success": true}, http.StatusOK)
+}
@@ -1,5 +1,6 @@
 import React, { useEffect, useState } from "react";
 import { Link, useNavigate } from "react-router-dom";
+import { csrfFetch } from "./csrf";
 import {
   BsMortarboardFill,
   BsPersonFill,
@@ -10,8 +11,12 @@ import {
   BsFileEarmarkTextFill,
   BsCheckCircleFill,
   BsUpload,
+  BsShieldCheck,
 } from "react-icons/bs";
 
+const hasBackoffice = (user) =>
+  Boolean(user?.permissions?.includes("applications:read"));
+
 export default function ApplyPage() {
   const navigate = useNavigate();
   const [applicationType, setApplicationType] = useState("Solo");
@@ -20,6 +25,7 @@ export default function ApplyPage() {
   const [submitting, setSubmitting] = useState(false);
   const [submitted, setSubmitted] = useState(false);
   const [subjects, setSubjects] = useState([]);
+  const [notice, setNotice] = useState(null);
   /* ===== AUTH STATE ===== */
   const [user, setUser] = useState(null);
   const [showAuth, setShowAuth] = useState(false);
@@ -31,19 +37,54 @@ useEffect(() => {
     .then(data => setSubjects(data || []))  // ← Ensure it's always an array
     .catch(() => setSubjects([]));
 
+  fetch("http://localhost:8080/privacy-notice")
+    .then(res => (res.ok ? res.json() : null))
+    .then(data => setNotice(data))
+    .catch(() => setNotice(null));
+
   // Check if user is logged in
-  fetch("http://localhost:8080/me", {
-    credentials: "include",
-  })
-    .then(res => res.json())
-    .then(data => {
-      if (data.loggedIn) {
-        setUser(data);
-      }
+  const checkLogin = () =>
+    fetch("http://localhost:8080/me", {
+      credentials: "include",
     })
-    .catch(() => {});
+      .then(res => res.json())
+      .then(data => {
+        if (data.loggedIn) {
+          setUser(data);
+        }
+        return data;
+      })
+      .catch(() => null);
+
+  // SSO sign-ins that still need the code from the authenticator app land here
+  if (new URLSearchParams(window.location.search).get("two_factor") === "required") {
+    window.history.replaceState(null, "", window.location.pathname);
+    secondFactor().then(ok => {
+      if (!ok) {
+        alert("❌ Authentication failed");
+        return;
+      }
+      checkLogin().then(data => {
+        if (hasBackoffice(data)) {
+          navigate("/backoffice");
+        }
+      });
+    });
+  } else {
+    checkLogin();
+  }
 }, []);
 
+  const secondFactor = async () => {
+    const code = prompt("Enter the code from your authenticator app or a recovery code");
+    const res = await csrfFetch("http://localhost:8080/login/2fa", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ code: code || "" }),
+    });
+    return res.ok;
+  };
+
   const validateForm = async (formData) => {
     // Validate phone number (8 digits)
     const phone = formData.get("phone") || "";
@@ -135,17 +176,28 @@ useEffect(() => {
   };
 
   const submitAuth = async () => {
-    const res = await fetch(
+    const res = await csrfFetch(
       `http://localhost:8080/${authMode}`,
       {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        credentials: "include",
         body: JSON.stringify(authForm),
       }
     );
 
     if (!res.ok) {
+      alert("❌ " + ((await res.text()).trim() || "Authentication failed"));
+      return;
+    }
+
+    if (authMode === "signup") {
+      alert("✅ Account created. Check your email to verify it, then log in.");
+      setAuthMode("login");
+      return;
+    }
+
+    const login = await res.json();
+    if (login.two_factor_required && !(await secondFactor())) {
       alert("❌ Authentication failed");
       return;
     }
@@ -158,14 +210,14 @@ useEffect(() => {
     setShowAuth(false);
     alert("✅ Success");
     
-    // If user is admin, redirect to backoffice
-    if (data.role === "admin") {
+    // If user can read applications, redirect to backoffice
+    if (hasBackoffice(data)) {
       navigate("/backoffice");
     }
   };
 
   const handleBackofficeClick = () => {
-    if (user && user.role === "admin") {
+    if (hasBackoffice(user)) {
       navigate("/backoffice");
     } else {
       setAuthMode("login");
@@ -174,9 +226,8 @@ useEffect(() => {
   };
 
   const handleLogout = async () => {
-    await fetch("http://localhost:8080/logout", {
+    await csrfFetch("http://localhost:8080/logout", {
       method: "POST",
-      credentials: "include",
     });
     setUser(null);
     alert("✅ Logged out successfully");
@@ -203,7 +254,7 @@ useEffect(() => {
             <button onClick={handleBackofficeClick} className="btn-primary">
               Log in
             </button>
-          ) : user.role === "admin" ? (
+          ) : hasBackoffice(user) ? (
             <button onClick={handleBackofficeClick} className="btn-primary">
               HR Backoffice
             </button>
@@ -322,7 +373,21 @@ useEffect(() => {
           </div>
         </div>
 
-        <button className="btn-submit" disabled={submitting}>
+        <div className="card">
+          <h3><BsShieldCheck /> Privacy Notice</h3>
+          <p style={{ whiteSpace: "pre-wrap" }}>{notice ? notice.body : "Loading..."}</p>
+          <input type="hidden" name="consent_version" value={notice?.version || ""} />
+          <label>
+            <input type="checkbox" name="consent" value="true" required />
+            {" "}I have read and accept the privacy notice *
+          </label>
+          <label>
+            <input type="checkbox" name="keep_profile" value="true" />
+            {" "}Keep my profile for future campaigns
+          </label>
+        </div>
+
+        <button className="btn-submit" disabled={submitting || !notice}>
           {submitting ? "Submitting..." : "Submit Application"}
         </button>
       </form>
@@ -362,6 +427,12 @@ useEffect(() => {
               Submit
             </button>
 
+            {authMode === "login" && (
+              <a className="btn-secondary" href="http://localhost:8080/oidc/login">
+                Sign in with SSO
+              </a>
+            )}
+
             <p
               style={{ cursor: "pointer", marginTop: 10 }}
               onClick={() =>
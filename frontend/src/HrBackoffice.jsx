@@ -12,6 +12,7 @@ import {
 } from "react-icons/bs";
 import "./hr-backoffice.css";
 import { Link, useNavigate } from "react-router-dom";
+import { csrfFetch } from "./csrf";
 
 export default function HrBackoffice() {
   const navigate = useNavigate();
@@ -69,12 +70,13 @@ export default function HrBackoffice() {
       .catch(() => setApplications([]));
 
     
-    // Fetch weekly applications count
-    fetch("http://localhost:8080/weekly-applications", {
+    // Fetch this week's applications count
+    const tz = Intl.DateTimeFormat().resolvedOptions().timeZone || "UTC";
+    fetch(`http://localhost:8080/stats?tz=${encodeURIComponent(tz)}`, {
       credentials: "include"
     })
       .then(res => res.json())
-      .then(data => setWeeklyCount(data.count))
+      .then(data => setWeeklyCount(data.week_over_week.current))
       .catch(err => console.error("Failed to fetch weekly count:", err));
   }, []);
   
@@ -84,9 +86,8 @@ useEffect(() => {
 
 
   const handleLogout = async () => {
-    await fetch("http://localhost:8080/logout", {
+    await csrfFetch("http://localhost:8080/logout", {
       method: "POST",
-      credentials: "include",
     });
     setUser(null);
     alert("Logged out successfully");
@@ -368,7 +369,7 @@ const filtered = useMemo(() => {
                   onClick={async () => {
                     if (!editSubjectId || !editSubjectName.trim()) return;
 
-                    const res = await fetch("http://localhost:8080/subjects", {
+                    const res = await csrfFetch("http://localhost:8080/subjects", {
                       method: "PUT",
                       headers: { "Content-Type": "application/json" },
                       body: JSON.stringify({
@@ -443,7 +444,7 @@ const filtered = useMemo(() => {
                     "Are you sure you want to delete the selected subjects?"
                   )) return;
 
-                  const res = await fetch("http://localhost:8080/subjects/delete", {
+                  const res = await csrfFetch("http://localhost:8080/subjects/delete", {
                     method: "DELETE",
                     headers: { "Content-Type": "application/json" },
                     body: JSON.stringify({ ids: selectedSubjects }),
@@ -500,7 +501,7 @@ const filtered = useMemo(() => {
             onClick={async () => {
               if (!newSubject.trim()) return;
 
-              const res = await fetch("http://localhost:8080/subjects", {
+              const res = await csrfFetch("http://localhost:8080/subjects", {
                 method: "POST",
                 headers: { "Content-Type": "application/json" },
                 body: JSON.stringify({ name: newSubject }),
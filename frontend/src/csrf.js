@@ -0,0 +1,30 @@
+// The backend rejects POST, PUT and DELETE requests made with cookies unless
+// they carry the CSRF token of the browser session.
+let token = null;
+
+async function csrfHeaders() {
+  if (!token) {
+    const res = await fetch("http://localhost:8080/csrf", { credentials: "include" });
+    token = (await res.json()).csrf_token;
+  }
+  return { "X-CSRF-Token": token };
+}
+
+// csrfFetch sends a request with cookies and the CSRF token. When the token
+// was refused, e.g. because its cookie expired while the tab stayed open, it
+// fetches a new one and tries once more.
+export async function csrfFetch(url, options = {}) {
+  const send = async () =>
+    fetch(url, {
+      ...options,
+      credentials: "include",
+      headers: { ...options.headers, ...(await csrfHeaders()) },
+    });
+
+  const res = await send();
+  if (res.status === 403 && (await res.clone().text()).includes("Invalid CSRF token")) {
+    token = null;
+    return send();
+  }
+  return res;
+}